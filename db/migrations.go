@@ -0,0 +1,24 @@
+// Package db holds the database migrations, embedded in the binary.
+package db
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+// files contains a migrations directory per database driver, with files named
+// <version>_<name>.up.sql and <version>_<name>.down.sql. Both directories hold
+// the same versions.
+//
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var files embed.FS
+
+// Migrations returns the migrations of the given driver, postgres or sqlite.
+func Migrations(driver string) (fs.FS, error) {
+	dir := "migrations/" + driver
+	if _, err := fs.Stat(files, dir); err != nil {
+		return nil, fmt.Errorf("no migrations for database driver %q", driver)
+	}
+	return fs.Sub(files, dir)
+}
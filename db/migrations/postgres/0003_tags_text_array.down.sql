@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS members_tags_idx;
+ALTER TABLE members ALTER COLUMN tags DROP NOT NULL;
+ALTER TABLE members ALTER COLUMN tags DROP DEFAULT;
+ALTER TABLE members ALTER COLUMN tags TYPE VARCHAR(255) USING tags::VARCHAR(255);
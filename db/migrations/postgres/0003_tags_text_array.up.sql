@@ -0,0 +1,21 @@
+-- tags used to be a VARCHAR(255) holding array literals
+DO $$
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'members' AND column_name = 'tags') <> 'ARRAY' THEN
+        ALTER TABLE members ALTER COLUMN tags DROP DEFAULT;
+        ALTER TABLE members ALTER COLUMN tags TYPE TEXT[] USING
+            CASE
+                WHEN tags IS NULL OR tags = '' THEN '{}'::TEXT[]
+                WHEN tags LIKE '{%}' THEN tags::TEXT[]
+                ELSE string_to_array(tags, ',')
+            END;
+    END IF;
+END $$;
+
+UPDATE members SET tags = '{}' WHERE tags IS NULL;
+ALTER TABLE members ALTER COLUMN tags SET DEFAULT '{}';
+ALTER TABLE members ALTER COLUMN tags SET NOT NULL;
+
+-- Serves the tag containment (@>) and overlap (&&) filters
+CREATE INDEX IF NOT EXISTS members_tags_idx ON members USING GIN (tags);
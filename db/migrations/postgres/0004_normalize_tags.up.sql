@@ -0,0 +1,10 @@
+-- Tags are trimmed, lower case and unique per member since tag management
+UPDATE members SET tags = ARRAY(
+    SELECT m.tag FROM (
+        SELECT lower(btrim(u.tag)) AS tag, u.n
+        FROM unnest(tags) WITH ORDINALITY AS u(tag, n)
+    ) m WHERE m.tag <> '' GROUP BY m.tag ORDER BY min(m.n)
+)
+WHERE EXISTS (
+    SELECT 1 FROM unnest(tags) AS t(tag) WHERE t.tag <> lower(btrim(t.tag)) OR t.tag = ''
+) OR cardinality(tags) <> (SELECT count(DISTINCT t.tag) FROM unnest(tags) AS t(tag));
@@ -0,0 +1,5 @@
+DROP INDEX members_validation_status_idx;
+ALTER TABLE members
+    DROP COLUMN validated_at,
+    DROP COLUMN validation_reason,
+    DROP COLUMN validation_status;
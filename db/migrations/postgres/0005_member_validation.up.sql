@@ -0,0 +1,6 @@
+-- Members are pending until the validation service gave its verdict
+ALTER TABLE members
+    ADD COLUMN validation_status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    ADD COLUMN validation_reason TEXT,
+    ADD COLUMN validated_at TIMESTAMPTZ;
+CREATE INDEX members_validation_status_idx ON members (validation_status);
@@ -0,0 +1,14 @@
+-- Side effects of member changes, written in the same transaction as the change
+-- and delivered by the outbox relay
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    member_id INT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    available_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    processed_at TIMESTAMPTZ
+);
+CREATE INDEX outbox_pending_idx ON outbox (available_at, id) WHERE processed_at IS NULL;
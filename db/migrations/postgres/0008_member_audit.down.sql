@@ -0,0 +1,2 @@
+DROP TABLE member_audit;
+DROP FUNCTION member_audit_append_only();
@@ -0,0 +1,25 @@
+-- Append-only history of member changes: who made them, when, in which request,
+-- and the before and after values of every changed field
+CREATE TABLE member_audit (
+    id BIGSERIAL PRIMARY KEY,
+    -- Not a foreign key, the history outlives the member
+    member_id INT NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255),
+    version INT NOT NULL,
+    changes JSONB NOT NULL,
+    at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX member_audit_member_idx ON member_audit (member_id, id);
+CREATE INDEX member_audit_actor_idx ON member_audit (actor, id);
+CREATE INDEX member_audit_at_idx ON member_audit (at);
+
+CREATE FUNCTION member_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'member_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER member_audit_append_only BEFORE UPDATE OR DELETE ON member_audit
+    FOR EACH ROW EXECUTE PROCEDURE member_audit_append_only();
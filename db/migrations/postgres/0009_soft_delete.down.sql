@@ -0,0 +1,3 @@
+DELETE FROM members WHERE deleted_at IS NOT NULL;
+DROP INDEX members_deleted_at_idx;
+ALTER TABLE members DROP COLUMN deleted_at;
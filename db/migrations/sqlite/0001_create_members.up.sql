@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS members (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    role VARCHAR(255),
+    duration INT,
+    tags VARCHAR(255)
+);
@@ -0,0 +1 @@
+ALTER TABLE members ADD COLUMN version INT NOT NULL DEFAULT 1;
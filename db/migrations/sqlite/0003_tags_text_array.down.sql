@@ -0,0 +1,16 @@
+-- tags keep their JSON form
+CREATE TABLE members_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    role VARCHAR(255),
+    duration INT,
+    tags VARCHAR(255),
+    version INT NOT NULL DEFAULT 1
+);
+
+INSERT INTO members_old (id, name, type, role, duration, tags, version)
+SELECT id, name, type, role, duration, tags, version FROM members;
+
+DROP TABLE members;
+ALTER TABLE members_old RENAME TO members;
@@ -0,0 +1,24 @@
+-- tags are a JSON array in SQLite. Columns cannot be changed in place, so the
+-- table is rebuilt, converting the comma-separated tags stored before.
+CREATE TABLE members_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    role VARCHAR(255),
+    duration INT,
+    tags TEXT NOT NULL DEFAULT '[]' CHECK (json_valid(tags) AND json_type(tags) = 'array'),
+    version INT NOT NULL DEFAULT 1
+);
+
+INSERT INTO members_new (id, name, type, role, duration, tags, version)
+SELECT id, name, type, role, duration,
+    CASE
+        WHEN tags IS NULL OR tags = '' THEN '[]'
+        WHEN json_valid(tags) AND substr(tags, 1, 1) = '[' THEN tags
+        ELSE json('["' || replace(replace(replace(tags, '\', '\\'), '"', '\"'), ',', '","') || '"]')
+    END,
+    version
+FROM members;
+
+DROP TABLE members;
+ALTER TABLE members_new RENAME TO members;
@@ -0,0 +1 @@
+-- The original spelling of normalized tags is not kept, there is nothing to revert
@@ -0,0 +1,8 @@
+-- Tags are trimmed, lower case and unique per member since tag management
+UPDATE members SET tags = (
+    SELECT json_group_array(m.tag) FROM (
+        SELECT lower(trim(t.value)) AS tag, min(t.key) AS n
+        FROM json_each(members.tags) AS t
+        WHERE lower(trim(t.value)) <> '' GROUP BY tag ORDER BY n
+    ) m
+);
@@ -0,0 +1,4 @@
+DROP INDEX members_validation_status_idx;
+ALTER TABLE members DROP COLUMN validated_at;
+ALTER TABLE members DROP COLUMN validation_reason;
+ALTER TABLE members DROP COLUMN validation_status;
@@ -0,0 +1,5 @@
+-- Members are pending until the validation service gave its verdict
+ALTER TABLE members ADD COLUMN validation_status VARCHAR(20) NOT NULL DEFAULT 'pending';
+ALTER TABLE members ADD COLUMN validation_reason TEXT;
+ALTER TABLE members ADD COLUMN validated_at TIMESTAMP;
+CREATE INDEX members_validation_status_idx ON members (validation_status);
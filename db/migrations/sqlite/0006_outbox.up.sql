@@ -0,0 +1,14 @@
+-- Side effects of member changes, written in the same transaction as the change
+-- and delivered by the outbox relay
+CREATE TABLE outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_type VARCHAR(50) NOT NULL,
+    member_id INT NOT NULL,
+    payload TEXT NOT NULL CHECK (json_valid(payload)),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    available_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    processed_at TIMESTAMP
+);
+CREATE INDEX outbox_pending_idx ON outbox (available_at, id) WHERE processed_at IS NULL;
@@ -0,0 +1,26 @@
+-- Append-only history of member changes: who made them, when, in which request,
+-- and the before and after values of every changed field
+CREATE TABLE member_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    -- Not a foreign key, the history outlives the member
+    member_id INT NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255),
+    version INT NOT NULL,
+    changes TEXT NOT NULL CHECK (json_valid(changes)),
+    at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX member_audit_member_idx ON member_audit (member_id, id);
+CREATE INDEX member_audit_actor_idx ON member_audit (actor, id);
+CREATE INDEX member_audit_at_idx ON member_audit (at);
+
+CREATE TRIGGER member_audit_no_update BEFORE UPDATE ON member_audit
+BEGIN
+    SELECT RAISE(ABORT, 'member_audit is append-only');
+END;
+
+CREATE TRIGGER member_audit_no_delete BEFORE DELETE ON member_audit
+BEGIN
+    SELECT RAISE(ABORT, 'member_audit is append-only');
+END;
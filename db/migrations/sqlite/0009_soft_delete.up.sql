@@ -0,0 +1,4 @@
+-- Deleted members are kept until the purge job removes them for good, so that
+-- they can be restored
+ALTER TABLE members ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX members_deleted_at_idx ON members (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/repositories"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	// headerActor tells who makes the request, recorded in the audit trail
+	headerActor = "X-Actor"
+	// anonymousActor is the actor of the requests without an X-Actor header
+	anonymousActor = "anonymous"
+	maxActorLength = 255
+)
+
+// withActor sets the actor of the request and its request ID in the context of
+// the request, for the audit trail. The API does not authenticate clients: by
+// default the actor is the one they claim in X-Actor, and with WithActorHeader
+// the one a trusted gateway sets in its header, which requests must have.
+func (api *API) withActor(next echo.HandlerFunc) echo.HandlerFunc {
+	header := headerActor
+	if api.actorHeader != "" {
+		header = api.actorHeader
+	}
+	return func(c echo.Context) error {
+		actor := models.Actor{
+			Name:      c.Request().Header.Get(header),
+			RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+		}
+		if actor.Name == "" && api.actorHeader != "" {
+			return newError(http.StatusUnauthorized, "unauthenticated", header+" is required")
+		}
+		if actor.Name == "" {
+			actor.Name = anonymousActor
+		}
+		if len(actor.Name) > maxActorLength {
+			return badRequest(header + " must be at most " + strconv.Itoa(maxActorLength) + " characters")
+		}
+		if actor.RequestID == "" {
+			actor.RequestID = c.Request().Header.Get(echo.HeaderXRequestID)
+		}
+
+		req := c.Request()
+		c.SetRequest(req.WithContext(repositories.ContextWithActor(req.Context(), actor)))
+		return next(c)
+	}
+}
+
+// isAdmin tells whether the actor of the request is one of the admins.
+func (api *API) isAdmin(c echo.Context) bool {
+	return api.admins[repositories.ActorFromContext(c.Request().Context()).Name]
+}
+
+// requireAdmin answers 403 to the requests of actors who are not admins.
+func (api *API) requireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !api.isAdmin(c) {
+			return forbidden("Only admins may read the audit trail of every member")
+		}
+		return next(c)
+	}
+}
+
+// GetMemberHistory lists the audit entries of a member, the latest first, paged
+// with before and limit. The history of deleted members is kept.
+func (api *API) GetMemberHistory(c echo.Context) error {
+	id, err := memberID(c)
+	if err != nil {
+		return err
+	}
+	filter, err := parseAuditPage(c)
+	if err != nil {
+		return badRequest(err.Error())
+	}
+	filter.MemberID = id
+
+	ctx := c.Request().Context()
+	entries, err := api.dbRepo.ListAudit(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 && filter.Before == 0 {
+		// Members created before the audit trail have no history
+		if _, err := api.dbRepo.GetMemberByID(ctx, id); err != nil {
+			return err
+		}
+	}
+	return c.JSON(http.StatusOK, auditPage(entries, filter))
+}
+
+// GetAudit lists the audit entries of every member, the latest first, filtered
+// by actor and time range and paged with before and limit. It is for admins.
+func (api *API) GetAudit(c echo.Context) error {
+	filter, err := parseAuditFilter(c)
+	if err != nil {
+		return badRequest(err.Error())
+	}
+
+	entries, err := api.dbRepo.ListAudit(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, auditPage(entries, filter))
+}
+
+func auditPage(entries []*models.AuditEntry, filter repositories.AuditFilter) models.AuditPage {
+	page := models.AuditPage{Entries: entries}
+	if len(entries) == filter.Limit {
+		page.NextBefore = entries[len(entries)-1].ID
+	}
+	return page
+}
+
+// parseAuditFilter reads the actor, from and to query parameters of the audit
+// trail, the times in RFC 3339 format, along with its paging.
+func parseAuditFilter(c echo.Context) (repositories.AuditFilter, error) {
+	filter, err := parseAuditPage(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.Actor = c.QueryParam("actor")
+	if filter.From, err = timeParam(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = timeParam(c, "to"); err != nil {
+		return filter, err
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, errors.New("from must be before to")
+	}
+	return filter, nil
+}
+
+// parseAuditPage reads the before and limit query parameters of audit entries.
+func parseAuditPage(c echo.Context) (repositories.AuditFilter, error) {
+	filter := repositories.AuditFilter{}
+	var err error
+	if filter.Limit, err = intParam(c, "limit", defaultPageSize); err != nil {
+		return filter, err
+	}
+	if filter.Limit < 1 || filter.Limit > maxPageSize {
+		return filter, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageSize))
+	}
+	if value := c.QueryParam("before"); value != "" {
+		if filter.Before, err = strconv.ParseInt(value, 10, 64); err != nil || filter.Before < 1 {
+			return filter, errors.New("invalid before parameter")
+		}
+	}
+	return filter, nil
+}
+
+func timeParam(c echo.Context, name string) (time.Time, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New(name + " must be an RFC 3339 time, such as 2023-05-01T12:00:00Z")
+	}
+	return t, nil
+}
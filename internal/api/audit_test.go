@@ -0,0 +1,174 @@
+package api
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/repositories"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seededAudit returns a server on the seeded repository after these changes:
+//
+//	09:03 alice@example.com promotes Carol to Director, in request req-1
+//	09:04 bob@example.com tags Bob with sql, in request req-2
+//	09:05 alice@example.com deletes Bob, in request req-3
+func seededAudit(t *testing.T) *echo.Echo {
+	repo := seededRepository(t)
+	alice := func(requestID string) context.Context {
+		return repositories.ContextWithActor(context.Background(), models.Actor{Name: "alice@example.com", RequestID: requestID})
+	}
+	bob := repositories.ContextWithActor(context.Background(), models.Actor{Name: "bob@example.com", RequestID: "req-2"})
+
+	carol := &models.Member{ID: 3, Name: "Carol", Type: models.MemberTypeEmployee, Role: "Director"}
+	require.NoError(t, repo.UpdateMember(alice("req-1"), carol))
+	_, err := repo.AddMemberTag(bob, 2, "sql")
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteMember(alice("req-3"), 2, 0))
+	return newServer(repo, WithAdmins(admin["X-Actor"]))
+}
+
+func runAuditTests(t *testing.T, tests []routeTest) {
+	runRouteTestsOn(t, seededAudit, tests)
+}
+
+func TestGetMemberHistory(t *testing.T) {
+	runAuditTests(t, []routeTest{
+		{
+			name: "updated member", method: http.MethodGet, target: "/members/3/history",
+			status: http.StatusOK,
+		},
+		{
+			name: "deleted member", method: http.MethodGet, target: "/members/2/history",
+			status: http.StatusOK,
+		},
+		{
+			name: "first page", method: http.MethodGet, target: "/members/2/history?limit=2",
+			status: http.StatusOK,
+		},
+		{
+			name: "next page", method: http.MethodGet, target: "/members/2/history?limit=2&before=5",
+			status: http.StatusOK,
+		},
+		{
+			name: "missing member", method: http.MethodGet, target: "/members/42/history",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "invalid limit", method: http.MethodGet, target: "/members/3/history?limit=0",
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestGetAudit(t *testing.T) {
+	runAuditTests(t, []routeTest{
+		{
+			name: "all", method: http.MethodGet, target: "/audit",
+			headers: admin,
+			status:  http.StatusOK,
+		},
+		{
+			name: "by actor", method: http.MethodGet, target: "/audit?actor=alice@example.com",
+			headers: admin,
+			status:  http.StatusOK,
+		},
+		{
+			name: "time range", method: http.MethodGet, target: "/audit?from=2023-05-01T09:02:00Z&to=2023-05-01T11:04:00%2B02:00",
+			headers: admin,
+			status:  http.StatusOK,
+		},
+		{
+			name: "invalid time", method: http.MethodGet, target: "/audit?from=yesterday",
+			headers: admin,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name: "empty range", method: http.MethodGet, target: "/audit?from=2023-05-01T10:00:00Z&to=2023-05-01T09:00:00Z",
+			headers: admin,
+			status:  http.StatusBadRequest,
+		},
+		{
+			name: "not an admin", method: http.MethodGet, target: "/audit",
+			headers: map[string]string{"X-Actor": "alice@example.com"},
+			status:  http.StatusForbidden,
+		},
+	})
+}
+
+func TestRequestsAreAudited(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		headers map[string]string
+		actor   string
+		request string
+	}{
+		{"actor", nil, map[string]string{"X-Actor": "alice@example.com", echo.HeaderXRequestID: "req-1"}, "alice@example.com", "req-1"},
+		{"anonymous", nil, nil, "anonymous", ""},
+		{"trusted header", []Option{WithActorHeader("X-Forwarded-User")},
+			map[string]string{"X-Forwarded-User": "bob@example.com", "X-Actor": "alice@example.com"}, "bob@example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			repo := seededRepository(t)
+			e := echo.New()
+			RegisterRoutes(e, repo, tt.opts...)
+			req := httptest.NewRequest(http.MethodPut, "/members/3", strings.NewReader(`{"name": "Carol", "type": "employee", "role": "Director"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			// Act
+			e.ServeHTTP(rec, req)
+
+			// Assert
+			require.Equal(t, http.StatusOK, rec.Code)
+			entries, err := repo.ListAudit(context.Background(), repositories.AuditFilter{MemberID: 3, Limit: 1})
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			assert.Equal(t, models.AuditUpdated, entries[0].Action)
+			assert.Equal(t, tt.actor, entries[0].Actor)
+			assert.Equal(t, tt.request, entries[0].RequestID)
+		})
+	}
+}
+
+func TestActorTooLong(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "rejected", method: http.MethodDelete, target: "/members/1",
+			headers: map[string]string{"X-Actor": strings.Repeat("a", 256)},
+			status:  http.StatusBadRequest,
+		},
+	})
+}
+
+func TestTrustedActorHeader(t *testing.T) {
+	runRouteTestsOn(t, func(t *testing.T) *echo.Echo {
+		e := echo.New()
+		RegisterRoutes(e, seededRepository(t), WithActorHeader("X-Forwarded-User"))
+		return e
+	}, []routeTest{
+		{
+			name: "set by the gateway", method: http.MethodDelete, target: "/members/1",
+			headers: map[string]string{"X-Forwarded-User": "alice@example.com"},
+			status:  http.StatusNoContent,
+		},
+		{
+			name: "missing", method: http.MethodDelete, target: "/members/1",
+			headers: map[string]string{"X-Actor": "alice@example.com"},
+			status:  http.StatusUnauthorized,
+		},
+	})
+}
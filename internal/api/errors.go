@@ -0,0 +1,117 @@
+package api
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/repositories"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// ErrorResponse is the body of every error returned by the API.
+type ErrorResponse struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Error is an error a handler returns to send a specific status and error code.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func badRequest(message string) *Error {
+	return newError(http.StatusBadRequest, "bad_request", message)
+}
+
+func forbidden(message string) *Error {
+	return newError(http.StatusForbidden, "forbidden", message)
+}
+
+// HTTPErrorHandler writes the errors returned by handlers and middleware as an ErrorResponse.
+// Repository errors are mapped to a status code by kind; anything unexpected is logged and
+// reported as an internal error without leaking its message.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	apiErr := toError(err)
+	if apiErr.Status == http.StatusInternalServerError {
+		c.Logger().Error(err)
+	}
+
+	body := ErrorResponse{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Details:   apiErr.Details,
+		RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+	}
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(apiErr.Status)
+	} else {
+		err = c.JSON(apiErr.Status, body)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+func toError(err error) *Error {
+	var apiErr *Error
+	var httpErr *echo.HTTPError
+	var validationErrs models.ValidationErrors
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.As(err, &validationErrs):
+		return &Error{
+			Status:  http.StatusUnprocessableEntity,
+			Code:    "validation_failed",
+			Message: "The member is not valid",
+			Details: validationErrs,
+		}
+	case errors.As(err, &httpErr):
+		message, ok := httpErr.Message.(string)
+		if !ok {
+			message = http.StatusText(httpErr.Code)
+		}
+		return newError(httpErr.Code, statusCode(httpErr.Code), message)
+	case errors.Is(err, repositories.ErrInvalidCursor):
+		return newError(http.StatusBadRequest, "invalid_cursor", err.Error())
+	case errors.Is(err, repositories.ErrNotFound):
+		return newError(http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, repositories.ErrVersionMismatch):
+		return preconditionFailed()
+	case errors.Is(err, repositories.ErrConflict):
+		return newError(http.StatusConflict, "conflict", err.Error())
+	case errors.Is(err, repositories.ErrValidation):
+		return newError(http.StatusUnprocessableEntity, "validation_failed", err.Error())
+	case errors.Is(err, repositories.ErrUnavailable):
+		return newError(http.StatusServiceUnavailable, "unavailable", "The database is unavailable")
+	case errors.Is(err, context.DeadlineExceeded):
+		return newError(http.StatusGatewayTimeout, "timeout", "Database query timed out")
+	default:
+		return newError(http.StatusInternalServerError, "internal", http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+// statusCode turns a status such as 405 into a code such as "method_not_allowed".
+func statusCode(status int) string {
+	return strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(strings.ToLower(http.StatusText(status)))
+}
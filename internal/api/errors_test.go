@@ -0,0 +1,90 @@
+package api
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingRepository fails every call it implements with err.
+type failingRepository struct {
+	repositories.MemberRepository
+	err error
+}
+
+func (r failingRepository) ListMembers(ctx context.Context, opts repositories.ListOptions) (*models.MemberList, error) {
+	return nil, r.err
+}
+
+func (r failingRepository) GetMemberByID(ctx context.Context, id int) (*models.Member, error) {
+	return nil, r.err
+}
+
+func TestRepositoryErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"unavailable", &repositories.Error{Kind: repositories.ErrUnavailable, Err: errors.New("connection refused")}, http.StatusServiceUnavailable},
+		{"timeout", fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusGatewayTimeout},
+		{"conflict", &repositories.Error{Kind: repositories.ErrConflict, Err: errors.New("duplicate key")}, http.StatusConflict},
+		{"version mismatch", &repositories.Error{Kind: repositories.ErrVersionMismatch}, http.StatusPreconditionFailed},
+		{"unexpected", errors.New("relation \"members\" does not exist"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			e := newServer(failingRepository{err: tt.err})
+			rec := httptest.NewRecorder()
+
+			// Act
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/members/1", nil))
+
+			// Assert
+			assert.Equal(t, tt.status, rec.Code)
+			assertGolden(t, rec.Body.Bytes())
+		})
+	}
+}
+
+func TestErrorsHaveRequestID(t *testing.T) {
+	// Arrange
+	e := echo.New()
+	e.Use(middleware.RequestID())
+	RegisterRoutes(e, seededRepository(t))
+	req := httptest.NewRequest(http.MethodGet, "/members/42", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-1")
+	rec := httptest.NewRecorder()
+
+	// Act
+	e.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"code": "not_found", "message": "member not found", "request_id": "req-1"}`, rec.Body.String())
+}
+
+func TestHeadErrorsHaveNoBody(t *testing.T) {
+	// Arrange
+	e := newServer(seededRepository(t))
+	rec := httptest.NewRecorder()
+
+	// Act
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/members/42", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"codelit/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// etag returns the entity tag of a member: its version, followed by the time of
+// its verdict once it was validated. Verdicts change the tag without changing
+// the version, so that If-None-Match sees them while If-Match, which only
+// compares the version, does not fail because of them.
+func etag(member *models.Member) string {
+	tag := strconv.Itoa(member.Version)
+	if member.ValidatedAt != nil {
+		tag += "-" + strconv.FormatInt(member.ValidatedAt.UnixMicro(), 10)
+	}
+	return `"` + tag + `"`
+}
+
+func setETag(c echo.Context, member *models.Member) {
+	c.Response().Header().Set("ETag", etag(member))
+}
+
+// ifMatchVersion returns the member version required by the If-Match header, or
+// 0 when the header is absent or "*" and any version may be written. The verdict
+// part of the tag is ignored.
+func ifMatchVersion(c echo.Context) (int, error) {
+	header := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	tag := strings.TrimPrefix(header, "W/")
+	number, _, _ := strings.Cut(strings.Trim(tag, `"`), "-")
+	version, err := strconv.Atoi(number)
+	if err != nil || version < 1 || !strings.HasPrefix(tag, `"`) {
+		return 0, badRequest("If-Match must hold the ETag of the member")
+	}
+	return version, nil
+}
+
+// notModified tells whether the If-None-Match header matches the member.
+func notModified(c echo.Context, member *models.Member) bool {
+	header := c.Request().Header.Get("If-None-Match")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag(member) {
+			return true
+		}
+	}
+	return false
+}
+
+func preconditionFailed() *Error {
+	return newError(http.StatusPreconditionFailed, "precondition_failed", "The member was modified since it was read")
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"codelit/internal/repositories"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	// eventBatchSize is the number of events read at a time by the change feed
+	eventBatchSize = 100
+	// keepAliveInterval is how long an idle change feed waits before sending a
+	// comment, so that proxies do not close it
+	keepAliveInterval = 15 * time.Second
+)
+
+// streamedEvents are the events of the change feed when no event_type is given.
+var streamedEvents = []string{outbox.MemberCreated, outbox.MemberUpdated, outbox.MemberDeleted, outbox.MemberRestored}
+
+// EventSource reads the member events in the order they were written, see
+// outbox.Feed.
+type EventSource interface {
+	Latest(ctx context.Context) (int64, error)
+	After(ctx context.Context, id int64, limit int) ([]outbox.Event, error)
+}
+
+// eventFilter selects the events of the change feed.
+type eventFilter struct {
+	types      map[string]bool
+	memberType string
+	tags       []string // any of them
+}
+
+func (f eventFilter) match(event outbox.Event, member *models.Member) bool {
+	if !f.types[event.Type] {
+		return false
+	}
+	if f.memberType != "" && member.Type != f.memberType {
+		return false
+	}
+	if len(f.tags) == 0 {
+		return true
+	}
+	for _, tag := range f.tags {
+		for _, memberTag := range member.Tags {
+			if tag == memberTag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StreamMemberEvents streams the changes of members as server-sent events, from
+// now on or after the event given by the Last-Event-ID header, until the client
+// goes away or the server shuts down.
+func (api *API) StreamMemberEvents(c echo.Context) error {
+	if api.events == nil {
+		return newError(http.StatusNotImplemented, "not_implemented", "The change feed needs a database")
+	}
+	filter, err := parseEventFilter(c)
+	if err != nil {
+		return badRequest(err.Error())
+	}
+	ctx := c.Request().Context()
+
+	after, err := lastEventID(c)
+	if err != nil {
+		return err
+	}
+	if after < 0 {
+		if after, err = api.events.Latest(ctx); err != nil {
+			return err
+		}
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	// Keeps nginx from buffering the stream
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	idle := time.Now()
+	for {
+		events, err := api.events.After(ctx, after, eventBatchSize)
+		if err != nil {
+			// The response started, the client reconnects with the last event it got
+			c.Logger().Error(err)
+			return nil
+		}
+		for _, event := range events {
+			after = event.ID
+			member, err := event.Member()
+			if err != nil {
+				c.Logger().Error(err)
+				continue
+			}
+			if !filter.match(event, member) {
+				continue
+			}
+			if err := writeEvent(res, event, member); err != nil {
+				return nil
+			}
+			idle = time.Now()
+		}
+		if len(events) == eventBatchSize {
+			continue
+		}
+
+		if time.Since(idle) >= keepAliveInterval {
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+			idle = time.Now()
+		}
+
+		timer := time.NewTimer(api.eventInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-api.closing:
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// writeEvent sends an event with the member as JSON data.
+func writeEvent(res *echo.Response, event outbox.Event, member *models.Member) error {
+	data, err := json.Marshal(member)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+// parseEventFilter reads the event_type, type and tags query parameters of the
+// change feed. Lists are comma separated.
+func parseEventFilter(c echo.Context) (eventFilter, error) {
+	filter := eventFilter{types: map[string]bool{}, memberType: c.QueryParam("type")}
+
+	types := listParam(c, "event_type")
+	if len(types) == 0 {
+		types = streamedEvents
+	}
+	for _, eventType := range types {
+		switch eventType {
+		case outbox.MemberCreated, outbox.MemberUpdated, outbox.MemberDeleted, outbox.MemberRestored,
+			outbox.MemberRevalidated, outbox.MemberValidated:
+			filter.types[eventType] = true
+		default:
+			return filter, fmt.Errorf("unknown event_type %q", eventType)
+		}
+	}
+
+	if filter.memberType != "" && filter.memberType != models.MemberTypeContractor && filter.memberType != models.MemberTypeEmployee {
+		return filter, fmt.Errorf("type must be 'contractor' or 'employee'")
+	}
+
+	for _, tag := range listParam(c, "tags") {
+		tag, err := repositories.NormalizeTag(tag)
+		if err != nil {
+			return filter, err
+		}
+		filter.tags = append(filter.tags, tag)
+	}
+	return filter, nil
+}
+
+// listParam returns the comma-separated values of a query parameter, which may
+// also be repeated.
+func listParam(c echo.Context, name string) []string {
+	values := []string{}
+	for _, value := range c.QueryParams()[name] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+// lastEventID returns the id of the last event the client got, from the
+// Last-Event-ID header that browsers send when they reconnect, or -1 when the
+// client starts watching.
+func lastEventID(c echo.Context) (int64, error) {
+	value := c.Request().Header.Get("Last-Event-ID")
+	if value == "" {
+		return -1, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, badRequest("Invalid Last-Event-ID header")
+	}
+	return id, nil
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bufio"
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"codelit/internal/repositories"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEvents is an EventSource of the events added by the test. It closes
+// watching, when set, once a stream started from the latest event.
+type fakeEvents struct {
+	mu       sync.Mutex
+	events   []outbox.Event
+	watching chan struct{}
+}
+
+func (f *fakeEvents) add(t *testing.T, eventType string, member *models.Member) {
+	payload, err := json.Marshal(member)
+	require.NoError(t, err)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, outbox.Event{ID: int64(len(f.events) + 1), Type: eventType, MemberID: member.ID, Payload: payload, CreatedAt: time.Now()})
+}
+
+func (f *fakeEvents) Latest(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.watching != nil {
+		close(f.watching)
+		f.watching = nil
+	}
+	return int64(len(f.events)), nil
+}
+
+func (f *fakeEvents) After(ctx context.Context, id int64, limit int) ([]outbox.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := []outbox.Event{}
+	for _, event := range f.events {
+		if event.ID > id && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// sentEvent is an event read from the change feed.
+type sentEvent struct {
+	id, event string
+	member    models.Member
+}
+
+// openStream serves the change feed of events and opens it with the headers.
+func openStream(t *testing.T, events EventSource, target string, headers map[string]string) (*http.Response, *bufio.Reader) {
+	e := echo.New()
+	RegisterRoutes(e, repositories.NewMemoryRepository(), WithEvents(events), WithEventInterval(time.Millisecond))
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+target, nil)
+	require.NoError(t, err)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	res, err := server.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+	return res, bufio.NewReader(res.Body)
+}
+
+// readEvent reads the next event of the stream, skipping comments.
+func readEvent(t *testing.T, stream *bufio.Reader) sentEvent {
+	t.Helper()
+	var sent sentEvent
+	for {
+		line, err := stream.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && sent.id != "":
+			return sent
+		case strings.HasPrefix(line, "id: "):
+			sent.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			sent.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &sent.member))
+		}
+	}
+}
+
+func TestStreamMemberEvents(t *testing.T) {
+	// Arrange
+	watching := make(chan struct{})
+	events := &fakeEvents{watching: watching}
+	events.add(t, outbox.MemberCreated, &models.Member{ID: 1, Name: "Alice", Version: 1})
+
+	// Act
+	res, stream := openStream(t, events, "/members/events", nil)
+	<-watching
+	events.add(t, outbox.MemberUpdated, &models.Member{ID: 1, Name: "Alice Smith", Version: 2})
+	sent := readEvent(t, stream)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get(echo.HeaderContentType))
+	assert.Equal(t, "no-cache", res.Header.Get("Cache-Control"))
+	assert.Equal(t, "2", sent.id)
+	assert.Equal(t, outbox.MemberUpdated, sent.event)
+	assert.Equal(t, "Alice Smith", sent.member.Name)
+}
+
+func TestStreamMemberEventsResumes(t *testing.T) {
+	// Arrange
+	events := &fakeEvents{}
+	events.add(t, outbox.MemberCreated, &models.Member{ID: 1, Name: "Alice", Version: 1})
+	events.add(t, outbox.MemberCreated, &models.Member{ID: 2, Name: "Bob", Version: 1})
+	events.add(t, outbox.MemberDeleted, &models.Member{ID: 1, Name: "Alice", Version: 1})
+
+	// Act
+	_, stream := openStream(t, events, "/members/events", map[string]string{"Last-Event-ID": "1"})
+	first := readEvent(t, stream)
+	second := readEvent(t, stream)
+
+	// Assert
+	assert.Equal(t, "2", first.id)
+	assert.Equal(t, "Bob", first.member.Name)
+	assert.Equal(t, "3", second.id)
+	assert.Equal(t, outbox.MemberDeleted, second.event)
+}
+
+func TestStreamMemberEventsFilters(t *testing.T) {
+	events := &fakeEvents{}
+	events.add(t, outbox.MemberCreated, &models.Member{ID: 1, Name: "Alice", Type: models.MemberTypeEmployee, Tags: []string{"go"}})
+	events.add(t, outbox.MemberRevalidated, &models.Member{ID: 1, Name: "Alice", Type: models.MemberTypeEmployee, Tags: []string{"go"}})
+	events.add(t, outbox.MemberCreated, &models.Member{ID: 2, Name: "Bob", Type: models.MemberTypeContractor, Tags: []string{"sql"}})
+	events.add(t, outbox.MemberDeleted, &models.Member{ID: 1, Name: "Alice", Type: models.MemberTypeEmployee, Tags: []string{"go"}})
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{"default types", "/members/events", "1"},
+		{"event type", "/members/events?event_type=member.revalidated,member.deleted", "2"},
+		{"member type", "/members/events?type=contractor", "3"},
+		{"tags", "/members/events?tags=rust,%20SQL", "3"},
+		{"repeated event type", "/members/events?event_type=member.deleted&event_type=member.updated", "4"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			_, stream := openStream(t, events, tt.target, map[string]string{"Last-Event-ID": "0"})
+			sent := readEvent(t, stream)
+
+			// Assert
+			assert.Equal(t, tt.want, sent.id)
+		})
+	}
+}
+
+func TestStreamMemberEventsRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		headers map[string]string
+	}{
+		{"unknown event type", "/members/events?event_type=member.renamed", nil},
+		{"unknown member type", "/members/events?type=intern", nil},
+		{"invalid tag", "/members/events?tags=" + strings.Repeat("a", 65), nil},
+		{"invalid last event id", "/members/events", map[string]string{"Last-Event-ID": "abc"}},
+		{"negative last event id", "/members/events", map[string]string{"Last-Event-ID": "-1"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			e := echo.New()
+			RegisterRoutes(e, repositories.NewMemoryRepository(), WithEvents(&fakeEvents{}))
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			// Act
+			e.ServeHTTP(rec, req)
+
+			// Assert
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+		})
+	}
+}
+
+func TestStreamMemberEventsWithoutEvents(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "not implemented", method: http.MethodGet, target: "/members/events",
+			status: http.StatusNotImplemented,
+		},
+	})
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/repositories"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// parseListOptions reads the paging, filtering and sorting query parameters of GET /members.
+// Paging accepts either limit/offset, page/page_size or a cursor from a previous page.
+func parseListOptions(c echo.Context) (repositories.ListOptions, error) {
+	opts := repositories.ListOptions{
+		Limit:    defaultPageSize,
+		Type:     c.QueryParam("type"),
+		Role:     c.QueryParam("role"),
+		Name:     strings.TrimSpace(c.QueryParam("name")),
+		Status:   c.QueryParam("validation_status"),
+		TagMatch: repositories.TagMatchAny,
+	}
+
+	if opts.Status != "" && !models.IsValidationStatus(opts.Status) {
+		return opts, errors.New("validation_status must be 'pending', 'valid', 'rejected' or 'error'")
+	}
+
+	var err error
+	if opts.Limit, err = intParam(c, "limit", opts.Limit); err != nil {
+		return opts, err
+	}
+	if opts.Limit, err = intParam(c, "page_size", opts.Limit); err != nil {
+		return opts, err
+	}
+	if opts.Limit < 1 || opts.Limit > maxPageSize {
+		return opts, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageSize))
+	}
+
+	if opts.Offset, err = intParam(c, "offset", 0); err != nil {
+		return opts, err
+	}
+	page, err := intParam(c, "page", 0)
+	if err != nil {
+		return opts, err
+	}
+	if page > 0 {
+		opts.Offset = (page - 1) * opts.Limit
+	}
+	if opts.Offset < 0 || page < 0 {
+		return opts, errors.New("offset and page must not be negative")
+	}
+
+	opts.Cursor = c.QueryParam("cursor")
+	if opts.Cursor != "" && (opts.Offset > 0 || page > 0) {
+		return opts, errors.New("cursor cannot be combined with offset or page")
+	}
+
+	opts.Tags = listParam(c, "tags")
+	if match := c.QueryParam("tags_match"); match != "" {
+		if match != repositories.TagMatchAny && match != repositories.TagMatchAll {
+			return opts, errors.New("tags_match must be 'any' or 'all'")
+		}
+		opts.TagMatch = match
+	}
+
+	if opts.Sort, err = repositories.ParseSort(c.QueryParam("sort")); err != nil {
+		return opts, err
+	}
+
+	if opts.IncludeDeleted, err = boolParam(c, "include_deleted"); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+func boolParam(c echo.Context, name string) (bool, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("invalid " + name + " parameter")
+	}
+	return b, nil
+}
+
+func intParam(c echo.Context, name string, fallback int) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("invalid " + name + " parameter")
+	}
+	return n, nil
+}
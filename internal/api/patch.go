@@ -0,0 +1,173 @@
+package api
+
+import (
+	"codelit/internal/models"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/labstack/echo"
+)
+
+const (
+	mimeMergePatch = "application/merge-patch+json"
+	mimeJSONPatch  = "application/json-patch+json"
+	// maxPatchSize bounds patch documents, far larger than any member
+	maxPatchSize = 64 << 10
+)
+
+// memberDocument is the document patches are applied to. Unlike models.Member it
+// has no omitted fields, so that JSON Patch operations can address all of them.
+type memberDocument struct {
+	ID       int      `json:"id"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Role     string   `json:"role"`
+	Duration int      `json:"duration"`
+	Tags     []string `json:"tags"`
+	Version  int      `json:"-"`
+
+	ValidationStatus string     `json:"-"`
+	ValidationReason string     `json:"-"`
+	ValidatedAt      *time.Time `json:"-"`
+	DeletedAt        *time.Time `json:"-"`
+}
+
+// PatchMember applies a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) to a
+// stored member, validates the result and only updates the columns that changed.
+func (api *API) PatchMember(c echo.Context) error {
+	id, err := memberID(c)
+	if err != nil {
+		return err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != mimeMergePatch && mediaType != mimeJSONPatch {
+		return newError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+			"Use "+mimeMergePatch+" or "+mimeJSONPatch)
+	}
+	patch, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, maxPatchSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return newError(http.StatusRequestEntityTooLarge, "request_too_large",
+			"Patch documents must be at most "+strconv.Itoa(maxPatchSize)+" bytes")
+	}
+	if err != nil {
+		return badRequest("Invalid patch document")
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	stored, err := api.dbRepo.GetMemberByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if version > 0 && version != stored.Version {
+		return preconditionFailed()
+	}
+
+	patched, err := applyPatch(stored, mediaType, patch)
+	if err != nil {
+		return err
+	}
+	if err := patched.Validate(); err != nil {
+		return err
+	}
+
+	// The update is conditional on the version that was patched, so that
+	// concurrent writes are never overwritten
+	fields := changedFields(stored, patched)
+	if len(fields) > 0 {
+		if err := api.dbRepo.UpdateMemberFields(ctx, patched, fields); err != nil {
+			return err
+		}
+	}
+	setETag(c, patched)
+	return c.JSON(http.StatusOK, patched)
+}
+
+func applyPatch(member *models.Member, mediaType string, patch []byte) (*models.Member, error) {
+	document := memberDocument(*member)
+	if document.Tags == nil {
+		document.Tags = []string{}
+	}
+	doc, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if mediaType == mimeMergePatch {
+		doc, err = jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			return nil, badRequest("Invalid merge patch document")
+		}
+	} else {
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, badRequest("Invalid JSON patch document")
+		}
+		doc, err = operations.Apply(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, newError(http.StatusConflict, "patch_test_failed", err.Error())
+		}
+		if err != nil {
+			return nil, newError(http.StatusUnprocessableEntity, "patch_failed", err.Error())
+		}
+	}
+
+	patched := memberDocument{}
+	if err := json.Unmarshal(doc, &patched); err != nil {
+		return nil, newError(http.StatusUnprocessableEntity, "patch_failed", "The patched member is not valid JSON for a member")
+	}
+	if patched.ID != member.ID {
+		return nil, newError(http.StatusUnprocessableEntity, "patch_failed", "The member ID cannot be changed")
+	}
+	patched.Version = member.Version
+	patched.ValidationStatus = member.ValidationStatus
+	patched.ValidationReason = member.ValidationReason
+	patched.ValidatedAt = member.ValidatedAt
+	result := models.Member(patched)
+	return &result, nil
+}
+
+// changedFields lists the member columns that differ between before and after.
+func changedFields(before, after *models.Member) []string {
+	fields := []string{}
+	if before.Name != after.Name {
+		fields = append(fields, "name")
+	}
+	if before.Type != after.Type {
+		fields = append(fields, "type")
+	}
+	if before.Role != after.Role {
+		fields = append(fields, "role")
+	}
+	if before.Duration != after.Duration {
+		fields = append(fields, "duration")
+	}
+	if !equalTags(before.Tags, after.Tags) {
+		fields = append(fields, "tags")
+	}
+	return fields
+}
+
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPatchMember(t *testing.T) {
+	mergePatch := map[string]string{"Content-Type": mimeMergePatch}
+	jsonPatch := map[string]string{"Content-Type": mimeJSONPatch}
+
+	runRouteTests(t, []routeTest{
+		{
+			name: "merge patch", method: http.MethodPatch, target: "/members/1",
+			body: `{"role": "Tech Lead", "tags": ["Go"]}`, headers: mergePatch,
+			status: http.StatusOK, want: map[string]string{"ETag": `"2"`},
+		},
+		{
+			name: "merge patch changing the type", method: http.MethodPatch, target: "/members/2",
+			body: `{"type": "employee", "role": "Engineer", "duration": null}`, headers: mergePatch,
+			status: http.StatusOK,
+		},
+		{
+			name: "json patch", method: http.MethodPatch, target: "/members/2",
+			body:    `[{"op": "test", "path": "/duration", "value": 6}, {"op": "add", "path": "/tags/-", "value": "sql"}]`,
+			headers: jsonPatch,
+			status:  http.StatusOK, want: map[string]string{"ETag": `"2"`},
+		},
+		{
+			name: "unchanged member", method: http.MethodPatch, target: "/members/3",
+			body: `{"name": "Carol"}`, headers: mergePatch,
+			status: http.StatusOK, want: map[string]string{"ETag": `"1"`},
+		},
+		{
+			name: "matching version", method: http.MethodPatch, target: "/members/3",
+			body: `{"role": "Director"}`, headers: map[string]string{"Content-Type": mimeMergePatch, "If-Match": `"1"`},
+			status: http.StatusOK,
+		},
+		{
+			name: "stale version", method: http.MethodPatch, target: "/members/3",
+			body: `{"role": "Director"}`, headers: map[string]string{"Content-Type": mimeMergePatch, "If-Match": `"2"`},
+			status: http.StatusPreconditionFailed,
+		},
+		{
+			name: "plain json", method: http.MethodPatch, target: "/members/1",
+			body:   `{"role": "Tech Lead"}`,
+			status: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "invalid merge patch", method: http.MethodPatch, target: "/members/1",
+			body: `{"role":`, headers: mergePatch,
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "invalid json patch", method: http.MethodPatch, target: "/members/1",
+			body: `{"op": "add"}`, headers: jsonPatch,
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "failed test operation", method: http.MethodPatch, target: "/members/2",
+			body: `[{"op": "test", "path": "/duration", "value": 3}]`, headers: jsonPatch,
+			status: http.StatusConflict,
+		},
+		{
+			name: "missing path", method: http.MethodPatch, target: "/members/2",
+			body: `[{"op": "remove", "path": "/tags/4"}]`, headers: jsonPatch,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "changed id", method: http.MethodPatch, target: "/members/1",
+			body: `{"id": 5}`, headers: mergePatch,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "invalid member", method: http.MethodPatch, target: "/members/1",
+			body: `{"type": "contractor"}`, headers: mergePatch,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "not found", method: http.MethodPatch, target: "/members/42",
+			body: `{"role": "Tech Lead"}`, headers: mergePatch,
+			status: http.StatusNotFound,
+		},
+		{
+			name: "bad id", method: http.MethodPatch, target: "/members/abc",
+			body: `{"role": "Tech Lead"}`, headers: mergePatch,
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "too large", method: http.MethodPatch, target: "/members/1",
+			body: `{"role": "` + strings.Repeat("a", maxPatchSize) + `"}`, headers: mergePatch,
+			status: http.StatusRequestEntityTooLarge,
+		},
+	})
+}
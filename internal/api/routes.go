@@ -5,43 +5,158 @@ import (
 	"codelit/internal/repositories"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo"
 )
 
 type API struct {
-	dbRepo repositories.MemberRepository
+	dbRepo          repositories.MemberRepository
+	events          EventSource
+	eventInterval   time.Duration
+	webhooks        WebhookStore
+	privateWebhooks bool
+	actorHeader     string
+	admins          map[string]bool
+
+	closeOnce sync.Once
+	closing   chan struct{}
+}
+
+type Option func(*API)
+
+// WithEvents serves the change feed of members read from events. Without it
+// GET /members/events is not implemented.
+func WithEvents(events EventSource) Option {
+	return func(api *API) {
+		api.events = events
+	}
 }
 
-func RegisterRoutes(e *echo.Echo, dbRepo repositories.MemberRepository) {
+// WithEventInterval sets how often the events of the change feed are polled.
+func WithEventInterval(interval time.Duration) Option {
+	return func(api *API) {
+		api.eventInterval = interval
+	}
+}
+
+// WithWebhooks serves the webhooks kept by store under /webhooks. Without it
+// the webhook requests are not implemented.
+func WithWebhooks(store WebhookStore) Option {
+	return func(api *API) {
+		api.webhooks = store
+	}
+}
+
+// WithPrivateWebhooks accepts the webhook URLs of loopback, private and
+// link-local hosts, which are refused by default. It is meant for local
+// development, with a sender using webhooks.WithPrivateNetworks.
+func WithPrivateWebhooks() Option {
+	return func(api *API) {
+		api.privateWebhooks = true
+	}
+}
+
+// WithActorHeader reads the actor of the audit trail from the given header,
+// which the gateway in front of the API sets once it authenticated the client,
+// and answers 401 to the requests without it. Without it the actor is the one
+// clients claim in X-Actor.
+func WithActorHeader(header string) Option {
+	return func(api *API) {
+		api.actorHeader = header
+	}
+}
+
+// WithAdmins sets the actors allowed to list the deleted members and to read the
+// audit trail of every member, which are forbidden to everyone else. Any client
+// can claim an X-Actor, so admins are only trusted with WithActorHeader.
+func WithAdmins(actors ...string) Option {
+	return func(api *API) {
+		for _, actor := range actors {
+			api.admins[actor] = true
+		}
+	}
+}
+
+func RegisterRoutes(e *echo.Echo, dbRepo repositories.MemberRepository, opts ...Option) {
 	api := &API{
-		dbRepo: dbRepo,
+		dbRepo:        dbRepo,
+		eventInterval: 500 * time.Millisecond,
+		admins:        map[string]bool{},
+		closing:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(api)
 	}
 
+	e.HTTPErrorHandler = HTTPErrorHandler
+	// The change feeds never end on their own, they are closed for the server
+	// to shut down gracefully
+	e.Server.RegisterOnShutdown(api.closeStreams)
+	e.Use(api.withActor)
+
 	e.GET("/members", api.GetMembers)
+	e.GET("/members/events", api.StreamMemberEvents)
 	e.GET("/members/:id", api.GetMemberByID)
 	e.POST("/members", api.CreateMember)
 	e.PUT("/members/:id", api.UpdateMember)
+	e.PATCH("/members/:id", api.PatchMember)
 	e.DELETE("/members/:id", api.DeleteMember)
+	e.POST("/members/:id/restore", api.RestoreMember)
+	e.POST("/members/:id/revalidate", api.RevalidateMember)
+	e.POST("/members/:id/tags", api.AddMemberTag)
+	e.DELETE("/members/:id/tags/:tag", api.RemoveMemberTag)
+	e.GET("/members/:id/history", api.GetMemberHistory)
+
+	e.GET("/tags", api.GetTags)
+	e.POST("/tags/merge", api.MergeTags)
+	e.POST("/tags/:tag/rename", api.RenameTag)
+
+	e.GET("/audit", api.GetAudit, api.requireAdmin)
+
+	webhooks := e.Group("/webhooks", api.requireWebhooks)
+	webhooks.GET("", api.GetWebhooks)
+	webhooks.POST("", api.CreateWebhook)
+	webhooks.GET("/:id", api.GetWebhook)
+	webhooks.PUT("/:id", api.UpdateWebhook)
+	webhooks.DELETE("/:id", api.DeleteWebhook)
+	webhooks.POST("/:id/replay", api.ReplayWebhook)
+	webhooks.GET("/:id/deliveries", api.GetWebhookDeliveries)
+	webhooks.GET("/:id/deliveries/:delivery_id", api.GetWebhookDelivery)
+	webhooks.POST("/:id/deliveries/:delivery_id/replay", api.ReplayWebhookDelivery)
 }
 
 func (api *API) GetMembers(c echo.Context) error {
-	members, err := api.dbRepo.GetAllMembers()
+	opts, err := parseListOptions(c)
+	if err != nil {
+		return badRequest(err.Error())
+	}
+	if opts.IncludeDeleted && !api.isAdmin(c) {
+		return forbidden("Only admins may list the deleted members")
+	}
+
+	members, err := api.dbRepo.ListMembers(c.Request().Context(), opts)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
+	c.Response().Header().Set("X-Total-Count", strconv.Itoa(members.Total))
 	return c.JSON(http.StatusOK, members)
 }
 
 func (api *API) GetMemberByID(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
+	id, err := memberID(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, "Invalid member ID")
+		return err
 	}
 
-	member, err := api.dbRepo.GetMemberByID(id)
+	member, err := api.dbRepo.GetMemberByID(c.Request().Context(), id)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, "Member not found")
+		return err
+	}
+	setETag(c, member)
+	if notModified(c, member) {
+		return c.NoContent(http.StatusNotModified)
 	}
 	return c.JSON(http.StatusOK, member)
 }
@@ -49,88 +164,109 @@ func (api *API) GetMemberByID(c echo.Context) error {
 func (api *API) CreateMember(c echo.Context) error {
 	member := new(models.Member)
 	if err := c.Bind(member); err != nil {
-		return c.JSON(http.StatusBadRequest, "Invalid member data")
+		return badRequest("Invalid member data")
 	}
 
-	notValid, err := checkMemberType(member, c)
-	if notValid {
+	if err := member.Validate(); err != nil {
 		return err
 	}
 
-	err = api.dbRepo.CreateMember(member)
+	err := api.dbRepo.CreateMember(c.Request().Context(), member)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
+	setETag(c, member)
 	return c.JSON(http.StatusCreated, member)
 }
 
 func (api *API) UpdateMember(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
+	id, err := memberID(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, "Invalid member ID")
+		return err
 	}
 
 	member := new(models.Member)
 	if err := c.Bind(member); err != nil {
-		return c.JSON(http.StatusBadRequest, "Invalid member data")
+		return badRequest("Invalid member data")
 	}
 
-	notValid, err := checkMemberType(member, c)
-	if notValid {
+	if err := member.Validate(); err != nil {
 		return err
 	}
 	member.ID = id
+	if member.Version, err = ifMatchVersion(c); err != nil {
+		return err
+	}
 
-	_, err = api.dbRepo.GetMemberByID(member.ID)
-	if err != nil {
-		return c.JSON(http.StatusNotFound, "Member does not exist")
-	} else {
-		api.dbRepo.UpdateMember(member)
-		return c.JSON(http.StatusOK, member)
+	if err := api.dbRepo.UpdateMember(c.Request().Context(), member); err != nil {
+		return err
 	}
+	setETag(c, member)
+	return c.JSON(http.StatusOK, member)
 }
 
 func (api *API) DeleteMember(c echo.Context) error {
-	id, err := strconv.Atoi(c.Param("id"))
+	id, err := memberID(c)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, "Invalid member ID")
+		return err
 	}
 
-	_, err = api.dbRepo.GetMemberByID(id)
+	version, err := ifMatchVersion(c)
 	if err != nil {
-		return c.JSON(http.StatusNotFound, nil)
-	} else {
-		api.dbRepo.DeleteMember(id)
-		return c.NoContent(http.StatusNoContent)
+		return err
 	}
+
+	if err := api.dbRepo.DeleteMember(c.Request().Context(), id, version); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
 }
 
-func checkMemberType(member *models.Member, c echo.Context) (bool, error) {
+// RestoreMember brings back a deleted member, with the next version. The restore
+// is conditional on If-Match like the other writes.
+func (api *API) RestoreMember(c echo.Context) error {
+	id, err := memberID(c)
+	if err != nil {
+		return err
+	}
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
 
-	if member.Name == "" {
-		return true, c.JSON(http.StatusBadRequest, "Members must have a name")
+	member, err := api.dbRepo.RestoreMember(c.Request().Context(), id, version)
+	if err != nil {
+		return err
 	}
+	setETag(c, member)
+	return c.JSON(http.StatusOK, member)
+}
 
-	if member.Type == "contractor" {
-		if member.Duration == 0 {
-			return true, c.JSON(http.StatusBadRequest, "Contractors must have a duration")
-		}
-		if member.Role != "" {
-			return true, c.JSON(http.StatusBadRequest, "Contractors must not have a role")
-		}
-	} else if member.Type == "employee" {
-		if member.Role == "" {
-			return true, c.JSON(http.StatusBadRequest, "Employees must have a role")
-		}
-		if member.Duration != 0 {
-			return true, c.JSON(http.StatusBadRequest, "Employees must not have a duration")
-		}
+// RevalidateMember sets the member back to pending and answers before the
+// validation service gave its new verdict.
+func (api *API) RevalidateMember(c echo.Context) error {
+	id, err := memberID(c)
+	if err != nil {
+		return err
+	}
 
-		member.Duration = 0 //setting a default value for employees
-	} else {
-		return true, c.JSON(http.StatusBadRequest, "Invalid member type, please use 'contractor' or 'employee'")
+	member, err := api.dbRepo.RevalidateMember(c.Request().Context(), id)
+	if err != nil {
+		return err
 	}
+	setETag(c, member)
+	return c.JSON(http.StatusAccepted, member)
+}
 
-	return false, nil
+func (api *API) closeStreams() {
+	api.closeOnce.Do(func() { close(api.closing) })
+}
+
+func memberID(c echo.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, badRequest("Invalid member ID")
+	}
+	return id, nil
 }
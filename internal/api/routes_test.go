@@ -0,0 +1,503 @@
+package api
+
+import (
+	"bytes"
+	"codelit/internal/models"
+	"codelit/internal/repositories"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// routeTest is a request sent to the API and the response it must get. The body
+// of the response is compared with testdata/<test name>.golden.
+type routeTest struct {
+	name    string
+	method  string
+	target  string
+	body    string
+	headers map[string]string
+	status  int
+	// header values the response must have
+	want map[string]string
+}
+
+// seededRepository returns a repository holding:
+//
+//	1 Alice, employee, Engineer, tags go and sql, valid
+//	2 Bob, contractor, 6 months, tag go, pending
+//	3 Carol, employee, Manager, no tags, pending
+//
+// Its changes are audited a minute apart from 2023-05-01 09:00 UTC.
+func seededRepository(t *testing.T) *repositories.MemoryRepository {
+	now := time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC)
+	repo := repositories.NewMemoryRepository(repositories.WithMemoryClock(func() time.Time {
+		at := now
+		now = now.Add(time.Minute)
+		return at
+	}))
+	for _, member := range []*models.Member{
+		{Name: "Alice", Type: models.MemberTypeEmployee, Role: "Engineer", Tags: []string{"go", "sql"}},
+		{Name: "Bob", Type: models.MemberTypeContractor, Duration: 6, Tags: []string{"go"}},
+		{Name: "Carol", Type: models.MemberTypeEmployee, Role: "Manager"},
+	} {
+		require.NoError(t, repo.CreateMember(context.Background(), member))
+	}
+	valid := models.Validation{Status: models.ValidationValid, At: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)}
+	require.NoError(t, repo.RecordValidation(context.Background(), 1, 1, valid))
+	return repo
+}
+
+func newServer(repo repositories.MemberRepository, opts ...Option) *echo.Echo {
+	e := echo.New()
+	RegisterRoutes(e, repo, opts...)
+	return e
+}
+
+// admin is the actor of the admin of the servers of the tests, who may list the
+// deleted members and read the audit trail.
+var admin = map[string]string{"X-Actor": "admin@example.com"}
+
+// runRouteTests sends each request to a server on a fresh seeded repository.
+func runRouteTests(t *testing.T, tests []routeTest) {
+	runRouteTestsOn(t, func(t *testing.T) *echo.Echo { return newServer(seededRepository(t)) }, tests)
+}
+
+// runRouteTestsOn sends each request to a fresh server returned by serve.
+func runRouteTestsOn(t *testing.T, serve func(t *testing.T) *echo.Echo, tests []routeTest) {
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			e := serve(t)
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body))
+			if tt.body != "" {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			// Act
+			e.ServeHTTP(rec, req)
+
+			// Assert
+			assert.Equal(t, tt.status, rec.Code)
+			for name, value := range tt.want {
+				assert.Equal(t, value, rec.Header().Get(name), name)
+			}
+			assertGolden(t, rec.Body.Bytes())
+		})
+	}
+}
+
+// assertGolden compares an indented copy of the JSON body with the golden file of
+// the test, rewriting the file instead when the -update flag is set. Empty bodies
+// have no golden file.
+func assertGolden(t *testing.T, body []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", t.Name()+".golden")
+	if len(body) == 0 {
+		_, err := os.Stat(golden)
+		assert.True(t, os.IsNotExist(err), "the response has no body but %s exists", golden)
+		return
+	}
+
+	indented := &bytes.Buffer{}
+	require.NoError(t, json.Indent(indented, body, "", "  "))
+	indented.WriteString("\n")
+
+	if *update {
+		require.NoError(t, os.MkdirAll(filepath.Dir(golden), 0755))
+		require.NoError(t, os.WriteFile(golden, indented.Bytes(), 0644))
+		return
+	}
+	expected, err := os.ReadFile(golden)
+	require.NoError(t, err, "run go test ./internal/api -update to create the golden files")
+	assert.Equal(t, string(expected), indented.String())
+}
+
+func TestGetMembers(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "all members", method: http.MethodGet, target: "/members",
+			status: http.StatusOK, want: map[string]string{"X-Total-Count": "3"},
+		},
+		{
+			name: "filtered by type and role", method: http.MethodGet, target: "/members?type=employee&role=Manager",
+			status: http.StatusOK, want: map[string]string{"X-Total-Count": "1"},
+		},
+		{
+			name: "filtered by name", method: http.MethodGet, target: "/members?name=o",
+			status: http.StatusOK, want: map[string]string{"X-Total-Count": "2"},
+		},
+		{
+			name: "filtered by all tags", method: http.MethodGet, target: "/members?tags=GO&tags=sql&tags_match=all",
+			status: http.StatusOK, want: map[string]string{"X-Total-Count": "1"},
+		},
+		{
+			name: "filtered by validation status", method: http.MethodGet, target: "/members?validation_status=pending",
+			status: http.StatusOK, want: map[string]string{"X-Total-Count": "2"},
+		},
+		{
+			name: "unknown validation status", method: http.MethodGet, target: "/members?validation_status=done",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "sorted and paged", method: http.MethodGet, target: "/members?sort=-name&page=2&page_size=1",
+			status: http.StatusOK, want: map[string]string{"X-Total-Count": "3"},
+		},
+		{
+			name: "first page with cursor", method: http.MethodGet, target: "/members?limit=2&sort=name",
+			status: http.StatusOK,
+		},
+		{
+			name: "limit out of range", method: http.MethodGet, target: "/members?limit=501",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "invalid offset", method: http.MethodGet, target: "/members?offset=first",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "invalid sort field", method: http.MethodGet, target: "/members?sort=tags",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "invalid tags match", method: http.MethodGet, target: "/members?tags=go&tags_match=some",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "cursor with offset", method: http.MethodGet, target: "/members?cursor=abc&offset=1",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "invalid cursor", method: http.MethodGet, target: "/members?cursor=abc",
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestGetMembersFollowsCursor(t *testing.T) {
+	// Arrange
+	e := newServer(seededRepository(t))
+	names := []string{}
+	target := "/members?limit=2&sort=-name"
+
+	// Act
+	for target != "" {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		list := models.MemberList{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+		for _, member := range list.Members {
+			names = append(names, member.Name)
+		}
+		target = ""
+		if list.NextCursor != "" {
+			target = "/members?limit=2&cursor=" + list.NextCursor
+		}
+	}
+
+	// Assert
+	assert.Equal(t, []string{"Carol", "Bob", "Alice"}, names)
+}
+
+func TestGetMemberByID(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "found", method: http.MethodGet, target: "/members/2",
+			status: http.StatusOK, want: map[string]string{"ETag": `"1"`},
+		},
+		{
+			name: "not modified", method: http.MethodGet, target: "/members/2",
+			headers: map[string]string{"If-None-Match": `"3", W/"1"`},
+			status:  http.StatusNotModified, want: map[string]string{"ETag": `"1"`},
+		},
+		{
+			name: "modified", method: http.MethodGet, target: "/members/2",
+			headers: map[string]string{"If-None-Match": `"3"`},
+			status:  http.StatusOK,
+		},
+		{
+			name: "validated since", method: http.MethodGet, target: "/members/1",
+			headers: map[string]string{"If-None-Match": `"1"`},
+			status:  http.StatusOK, want: map[string]string{"ETag": `"1-1682942400000000"`},
+		},
+		{
+			name: "not modified since validated", method: http.MethodGet, target: "/members/1",
+			headers: map[string]string{"If-None-Match": `"1-1682942400000000"`},
+			status:  http.StatusNotModified,
+		},
+		{
+			name: "not found", method: http.MethodGet, target: "/members/42",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "bad id", method: http.MethodGet, target: "/members/abc",
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestCreateMember(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "employee", method: http.MethodPost, target: "/members",
+			body:   `{"name": "Dave", "type": "employee", "role": "Designer", "tags": ["UX", " Figma"]}`,
+			status: http.StatusCreated, want: map[string]string{"ETag": `"1"`},
+		},
+		{
+			name: "contractor", method: http.MethodPost, target: "/members",
+			body:   `{"name": "Erin", "type": "contractor", "duration": 12}`,
+			status: http.StatusCreated,
+		},
+		{
+			name: "contractor without duration", method: http.MethodPost, target: "/members",
+			body:   `{"name": "Erin", "type": "contractor"}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "contractor with role", method: http.MethodPost, target: "/members",
+			body:   `{"name": "Erin", "type": "contractor", "duration": 12, "role": "Engineer"}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "employee with duration", method: http.MethodPost, target: "/members",
+			body:   `{"name": "Dave", "type": "employee", "role": "Designer", "duration": 3}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "employee without role", method: http.MethodPost, target: "/members",
+			body:   `{"name": "Dave", "type": "employee"}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "unknown type", method: http.MethodPost, target: "/members",
+			body:   `{"name": "Dave", "type": "intern"}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "missing name", method: http.MethodPost, target: "/members",
+			body:   `{"type": "contractor", "duration": 12}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "empty tag", method: http.MethodPost, target: "/members",
+			body:   `{"name": "Erin", "type": "contractor", "duration": 12, "tags": [" "]}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "tag repeated in another case", method: http.MethodPost, target: "/members",
+			body:   `{"name": "Erin", "type": "contractor", "duration": 12, "tags": ["Go", " go"]}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "invalid json", method: http.MethodPost, target: "/members",
+			body:   `{"name": "Erin",`,
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestUpdateMember(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "unconditional", method: http.MethodPut, target: "/members/1",
+			body:   `{"name": "Alice", "type": "employee", "role": "Tech Lead", "tags": ["go"]}`,
+			status: http.StatusOK, want: map[string]string{"ETag": `"2"`},
+		},
+		{
+			name: "validated since read", method: http.MethodPut, target: "/members/1",
+			body:    `{"name": "Alice", "type": "employee", "role": "Tech Lead", "tags": ["go"]}`,
+			headers: map[string]string{"If-Match": `"1"`},
+			status:  http.StatusOK, want: map[string]string{"ETag": `"2"`},
+		},
+		{
+			name: "validated version", method: http.MethodPut, target: "/members/1",
+			body:    `{"name": "Alice", "type": "employee", "role": "Tech Lead", "tags": ["go"]}`,
+			headers: map[string]string{"If-Match": `"1-1682942400000000"`},
+			status:  http.StatusOK, want: map[string]string{"ETag": `"2"`},
+		},
+		{
+			name: "matching version", method: http.MethodPut, target: "/members/2",
+			body:    `{"name": "Bob", "type": "contractor", "duration": 9}`,
+			headers: map[string]string{"If-Match": `"1"`},
+			status:  http.StatusOK, want: map[string]string{"ETag": `"2"`},
+		},
+		{
+			name: "validation ignored", method: http.MethodPut, target: "/members/2",
+			body:   `{"name": "Bob", "type": "contractor", "duration": 9, "validation_status": "valid"}`,
+			status: http.StatusOK, want: map[string]string{"ETag": `"2"`},
+		},
+		{
+			name: "stale version", method: http.MethodPut, target: "/members/2",
+			body:    `{"name": "Bob", "type": "contractor", "duration": 9}`,
+			headers: map[string]string{"If-Match": `"7"`},
+			status:  http.StatusPreconditionFailed,
+		},
+		{
+			name: "malformed if-match", method: http.MethodPut, target: "/members/2",
+			body:    `{"name": "Bob", "type": "contractor", "duration": 9}`,
+			headers: map[string]string{"If-Match": "1"},
+			status:  http.StatusBadRequest,
+		},
+		{
+			name: "changed into an employee without role", method: http.MethodPut, target: "/members/2",
+			body:   `{"name": "Bob", "type": "employee"}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "not found", method: http.MethodPut, target: "/members/42",
+			body:   `{"name": "Bob", "type": "contractor", "duration": 9}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name: "bad id", method: http.MethodPut, target: "/members/abc",
+			body:   `{"name": "Bob", "type": "contractor", "duration": 9}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "invalid json", method: http.MethodPut, target: "/members/2",
+			body:   `[]`,
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestDeleteMember(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "deleted", method: http.MethodDelete, target: "/members/3",
+			status: http.StatusNoContent,
+		},
+		{
+			name: "matching version", method: http.MethodDelete, target: "/members/3",
+			headers: map[string]string{"If-Match": `"1"`},
+			status:  http.StatusNoContent,
+		},
+		{
+			name: "stale version", method: http.MethodDelete, target: "/members/3",
+			headers: map[string]string{"If-Match": `"2"`},
+			status:  http.StatusPreconditionFailed,
+		},
+		{
+			name: "not found", method: http.MethodDelete, target: "/members/42",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "bad id", method: http.MethodDelete, target: "/members/abc",
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+// serveDeletedBob serves the seeded repository with Bob deleted.
+func serveDeletedBob(t *testing.T) *echo.Echo {
+	repo := seededRepository(t)
+	require.NoError(t, repo.DeleteMember(context.Background(), 2, 0))
+	return newServer(repo, WithAdmins(admin["X-Actor"]))
+}
+
+func TestGetDeletedMembers(t *testing.T) {
+	runRouteTestsOn(t, serveDeletedBob, []routeTest{
+		{
+			name: "hidden", method: http.MethodGet, target: "/members",
+			status: http.StatusOK, want: map[string]string{"X-Total-Count": "2"},
+		},
+		{
+			name: "included", method: http.MethodGet, target: "/members?include_deleted=true",
+			headers: admin,
+			status:  http.StatusOK, want: map[string]string{"X-Total-Count": "3"},
+		},
+		{
+			name: "included for another actor", method: http.MethodGet, target: "/members?include_deleted=true",
+			headers: map[string]string{"X-Actor": "alice@example.com"},
+			status:  http.StatusForbidden,
+		},
+		{
+			name: "invalid include deleted", method: http.MethodGet, target: "/members?include_deleted=maybe",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "get", method: http.MethodGet, target: "/members/2",
+			status: http.StatusNotFound,
+		},
+	})
+}
+
+func TestRestoreMember(t *testing.T) {
+	runRouteTestsOn(t, serveDeletedBob, []routeTest{
+		{
+			name: "restored", method: http.MethodPost, target: "/members/2/restore",
+			status: http.StatusOK, want: map[string]string{"ETag": `"2"`},
+		},
+		{
+			name: "conditional", method: http.MethodPost, target: "/members/2/restore",
+			headers: map[string]string{"If-Match": `"1"`},
+			status:  http.StatusOK, want: map[string]string{"ETag": `"2"`},
+		},
+		{
+			name: "stale version", method: http.MethodPost, target: "/members/2/restore",
+			headers: map[string]string{"If-Match": `"7"`},
+			status:  http.StatusPreconditionFailed,
+		},
+		{
+			name: "not deleted", method: http.MethodPost, target: "/members/1/restore",
+			status: http.StatusConflict,
+		},
+		{
+			name: "not found", method: http.MethodPost, target: "/members/42/restore",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "bad id", method: http.MethodPost, target: "/members/abc/restore",
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestRevalidateMember(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "revalidated", method: http.MethodPost, target: "/members/1/revalidate",
+			status: http.StatusAccepted, want: map[string]string{"ETag": `"1"`},
+		},
+		{
+			name: "not found", method: http.MethodPost, target: "/members/42/revalidate",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "bad id", method: http.MethodPost, target: "/members/abc/revalidate",
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestUnknownRoute(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "not found", method: http.MethodGet, target: "/teams",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "method not allowed", method: http.MethodPost, target: "/members/1",
+			status: http.StatusMethodNotAllowed,
+		},
+	})
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"codelit/internal/repositories"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo"
+)
+
+type renameTagRequest struct {
+	Name string `json:"name"`
+}
+
+type mergeTagsRequest struct {
+	Tags []string `json:"tags"`
+	Into string   `json:"into"`
+}
+
+type addTagRequest struct {
+	Tag string `json:"tag"`
+}
+
+// tagChange is the response of the operations that change a tag on all members.
+type tagChange struct {
+	Tag     string `json:"tag"`
+	Members int    `json:"members"`
+}
+
+func (api *API) GetTags(c echo.Context) error {
+	tags, err := api.dbRepo.ListTags(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, tags)
+}
+
+// RenameTag renames a tag on every member. Renaming into an existing tag merges both.
+func (api *API) RenameTag(c echo.Context) error {
+	tag, err := tagParam(c)
+	if err != nil {
+		return err
+	}
+	req := new(renameTagRequest)
+	if err := c.Bind(req); err != nil {
+		return badRequest("Invalid rename data")
+	}
+
+	count, err := api.dbRepo.MergeTags(c.Request().Context(), []string{tag}, req.Name)
+	if err != nil {
+		return err
+	}
+	// The repository accepted the name, so it normalizes without error
+	name, _ := repositories.NormalizeTag(req.Name)
+	return c.JSON(http.StatusOK, tagChange{Tag: name, Members: count})
+}
+
+// MergeTags replaces several tags by a single one on every member.
+func (api *API) MergeTags(c echo.Context) error {
+	req := new(mergeTagsRequest)
+	if err := c.Bind(req); err != nil {
+		return badRequest("Invalid merge data")
+	}
+	if len(req.Tags) == 0 {
+		return badRequest("At least one tag to merge is required")
+	}
+
+	count, err := api.dbRepo.MergeTags(c.Request().Context(), req.Tags, req.Into)
+	if err != nil {
+		return err
+	}
+	into, _ := repositories.NormalizeTag(req.Into)
+	return c.JSON(http.StatusOK, tagChange{Tag: into, Members: count})
+}
+
+func (api *API) AddMemberTag(c echo.Context) error {
+	id, err := memberID(c)
+	if err != nil {
+		return err
+	}
+	req := new(addTagRequest)
+	if err := c.Bind(req); err != nil {
+		return badRequest("Invalid tag data")
+	}
+
+	member, err := api.dbRepo.AddMemberTag(c.Request().Context(), id, req.Tag)
+	if err != nil {
+		return err
+	}
+	setETag(c, member)
+	return c.JSON(http.StatusOK, member)
+}
+
+func (api *API) RemoveMemberTag(c echo.Context) error {
+	id, err := memberID(c)
+	if err != nil {
+		return err
+	}
+	tag, err := tagParam(c)
+	if err != nil {
+		return err
+	}
+
+	member, err := api.dbRepo.RemoveMemberTag(c.Request().Context(), id, tag)
+	if err != nil {
+		return err
+	}
+	setETag(c, member)
+	return c.JSON(http.StatusOK, member)
+}
+
+// tagParam returns the tag of the path. Echo decodes the path parameters, except
+// when it routed on the raw path, as it does when the tag holds an encoded slash.
+func tagParam(c echo.Context) (string, error) {
+	tag := c.Param("tag")
+	if c.Request().URL.RawPath == "" {
+		return tag, nil
+	}
+	tag, err := url.PathUnescape(tag)
+	if err != nil {
+		return "", badRequest("Invalid tag")
+	}
+	return tag, nil
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTags(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "counts", method: http.MethodGet, target: "/tags",
+			status: http.StatusOK,
+		},
+	})
+}
+
+func TestRenameTag(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "renamed", method: http.MethodPost, target: "/tags/go/rename",
+			body:   `{"name": "Golang"}`,
+			status: http.StatusOK,
+		},
+		{
+			name: "escaped tag", method: http.MethodPost, target: "/tags/%53QL/rename",
+			body:   `{"name": "postgres"}`,
+			status: http.StatusOK,
+		},
+		{
+			name: "unused tag", method: http.MethodPost, target: "/tags/rust/rename",
+			body:   `{"name": "go"}`,
+			status: http.StatusOK,
+		},
+		{
+			name: "empty name", method: http.MethodPost, target: "/tags/go/rename",
+			body:   `{"name": " "}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "invalid json", method: http.MethodPost, target: "/tags/go/rename",
+			body:   `"go"`,
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestMergeTags(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "merged", method: http.MethodPost, target: "/tags/merge",
+			body:   `{"tags": ["go", "sql"], "into": "backend"}`,
+			status: http.StatusOK,
+		},
+		{
+			name: "no tags", method: http.MethodPost, target: "/tags/merge",
+			body:   `{"into": "backend"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "empty target", method: http.MethodPost, target: "/tags/merge",
+			body:   `{"tags": ["go"]}`,
+			status: http.StatusUnprocessableEntity,
+		},
+	})
+}
+
+func TestAddMemberTag(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "added", method: http.MethodPost, target: "/members/3/tags",
+			body:   `{"tag": " Leadership "}`,
+			status: http.StatusOK, want: map[string]string{"ETag": `"2"`},
+		},
+		{
+			name: "already there", method: http.MethodPost, target: "/members/1/tags",
+			body:   `{"tag": "GO"}`,
+			status: http.StatusOK, want: map[string]string{"ETag": `"1-1682942400000000"`},
+		},
+		{
+			name: "empty tag", method: http.MethodPost, target: "/members/1/tags",
+			body:   `{"tag": ""}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "not found", method: http.MethodPost, target: "/members/42/tags",
+			body:   `{"tag": "go"}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name: "bad id", method: http.MethodPost, target: "/members/abc/tags",
+			body:   `{"tag": "go"}`,
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestRemoveMemberTag(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "removed", method: http.MethodDelete, target: "/members/1/tags/SQL",
+			status: http.StatusOK, want: map[string]string{"ETag": `"2"`},
+		},
+		{
+			name: "not there", method: http.MethodDelete, target: "/members/3/tags/go",
+			status: http.StatusOK, want: map[string]string{"ETag": `"1"`},
+		},
+		{
+			name: "not found", method: http.MethodDelete, target: "/members/42/tags/go",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "bad id", method: http.MethodDelete, target: "/members/abc/tags/go",
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestEscapedTags(t *testing.T) {
+	serve := func(t *testing.T) *echo.Echo {
+		repo := seededRepository(t)
+		for _, tag := range []string{"100%", "%41", "ci/cd"} {
+			_, err := repo.AddMemberTag(context.Background(), 2, tag)
+			require.NoError(t, err)
+		}
+		return newServer(repo)
+	}
+	runRouteTestsOn(t, serve, []routeTest{
+		{
+			name: "percent sign", method: http.MethodPost, target: "/tags/100%25/rename",
+			body:   `{"name": "full"}`,
+			status: http.StatusOK,
+		},
+		{
+			name: "escaped percent sign", method: http.MethodPost, target: "/tags/%2541/rename",
+			body:   `{"name": "a"}`,
+			status: http.StatusOK,
+		},
+		{
+			name: "slash", method: http.MethodPost, target: "/tags/ci%2Fcd/rename",
+			body:   `{"name": "devops"}`,
+			status: http.StatusOK,
+		},
+		{
+			name: "removed with a slash", method: http.MethodDelete, target: "/members/2/tags/ci%2Fcd",
+			status: http.StatusOK, want: map[string]string{"ETag": `"5"`},
+		},
+	})
+}
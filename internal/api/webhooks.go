@@ -0,0 +1,266 @@
+package api
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/webhooks"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo"
+)
+
+// WebhookStore keeps the webhooks and their deliveries, see webhooks.Store.
+type WebhookStore interface {
+	Create(ctx context.Context, w *webhooks.Webhook) error
+	Get(ctx context.Context, id int) (*webhooks.Webhook, error)
+	List(ctx context.Context) ([]*webhooks.Webhook, error)
+	Update(ctx context.Context, w *webhooks.Webhook) error
+	Delete(ctx context.Context, id int) error
+	Deliveries(ctx context.Context, webhookID int, filter webhooks.DeliveryFilter) ([]*webhooks.Delivery, error)
+	Delivery(ctx context.Context, webhookID int, id int64) (*webhooks.Delivery, error)
+	Replay(ctx context.Context, webhookID int, id int64) (*webhooks.Delivery, error)
+	ReplayDead(ctx context.Context, webhookID int) (int, error)
+}
+
+type webhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+	Active     *bool    `json:"active"`
+}
+
+// webhook returns the webhook of the request, active unless told otherwise.
+func (req *webhookRequest) webhook() *webhooks.Webhook {
+	w := &webhooks.Webhook{URL: req.URL, Secret: req.Secret, EventTypes: req.EventTypes, Active: true}
+	if req.Active != nil {
+		w.Active = *req.Active
+	}
+	return w
+}
+
+// deliveryList is a page of the deliveries of a webhook. NextBefore is the
+// before parameter of the next page, zero on the last page.
+type deliveryList struct {
+	Deliveries []*webhooks.Delivery `json:"deliveries"`
+	NextBefore int64                `json:"next_before,omitempty"`
+}
+
+type replayResult struct {
+	Replayed int `json:"replayed"`
+}
+
+// requireWebhooks answers 501 to the webhook requests when the API has no
+// webhook store.
+func (api *API) requireWebhooks(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if api.webhooks == nil {
+			return newError(http.StatusNotImplemented, "not_implemented", "Webhooks need a database")
+		}
+		return next(c)
+	}
+}
+
+func (api *API) GetWebhooks(c echo.Context) error {
+	list, err := api.webhooks.List(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	for _, w := range list {
+		w.Secret = ""
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+func (api *API) GetWebhook(c echo.Context) error {
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+
+	w, err := api.webhooks.Get(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+	w.Secret = ""
+	return c.JSON(http.StatusOK, w)
+}
+
+// CreateWebhook subscribes a URL to member events. The response is the only one
+// holding the secret the deliveries are signed with.
+func (api *API) CreateWebhook(c echo.Context) error {
+	req := new(webhookRequest)
+	if err := c.Bind(req); err != nil {
+		return badRequest("Invalid webhook data")
+	}
+
+	w := req.webhook()
+	if err := api.validateWebhook(w); err != nil {
+		return err
+	}
+	if err := api.webhooks.Create(c.Request().Context(), w); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, w)
+}
+
+// UpdateWebhook replaces a webhook. Its secret is kept unless a new one is given.
+func (api *API) UpdateWebhook(c echo.Context) error {
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+	req := new(webhookRequest)
+	if err := c.Bind(req); err != nil {
+		return badRequest("Invalid webhook data")
+	}
+
+	w := req.webhook()
+	if err := api.validateWebhook(w); err != nil {
+		return err
+	}
+	w.ID = id
+	if err := api.webhooks.Update(c.Request().Context(), w); err != nil {
+		return err
+	}
+	w.Secret = ""
+	return c.JSON(http.StatusOK, w)
+}
+
+func (api *API) DeleteWebhook(c echo.Context) error {
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+
+	if err := api.webhooks.Delete(c.Request().Context(), id); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries lists the deliveries of a webhook, the latest first,
+// filtered by status and paged with before and limit.
+func (api *API) GetWebhookDeliveries(c echo.Context) error {
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+	filter, err := parseDeliveryFilter(c)
+	if err != nil {
+		return badRequest(err.Error())
+	}
+
+	deliveries, err := api.webhooks.Deliveries(c.Request().Context(), id, filter)
+	if err != nil {
+		return err
+	}
+	list := deliveryList{Deliveries: deliveries}
+	if len(deliveries) == filter.Limit {
+		list.NextBefore = deliveries[len(deliveries)-1].ID
+	}
+	return c.JSON(http.StatusOK, list)
+}
+
+// GetWebhookDelivery returns a delivery with the log of its attempts.
+func (api *API) GetWebhookDelivery(c echo.Context) error {
+	id, deliveryID, err := deliveryIDs(c)
+	if err != nil {
+		return err
+	}
+
+	delivery, err := api.webhooks.Delivery(c.Request().Context(), id, deliveryID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, delivery)
+}
+
+// ReplayWebhookDelivery queues a delivery again, whatever its status, and
+// answers before it is posted.
+func (api *API) ReplayWebhookDelivery(c echo.Context) error {
+	id, deliveryID, err := deliveryIDs(c)
+	if err != nil {
+		return err
+	}
+
+	delivery, err := api.webhooks.Replay(c.Request().Context(), id, deliveryID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusAccepted, delivery)
+}
+
+// ReplayWebhook queues the dead deliveries of a webhook again.
+func (api *API) ReplayWebhook(c echo.Context) error {
+	id, err := webhookID(c)
+	if err != nil {
+		return err
+	}
+
+	n, err := api.webhooks.ReplayDead(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusAccepted, replayResult{Replayed: n})
+}
+
+func (api *API) validateWebhook(w *webhooks.Webhook) error {
+	err := w.Validate(api.privateWebhooks)
+	var validationErrs models.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return &Error{
+			Status:  http.StatusUnprocessableEntity,
+			Code:    "validation_failed",
+			Message: "The webhook is not valid",
+			Details: validationErrs,
+		}
+	}
+	return err
+}
+
+// parseDeliveryFilter reads the status, before and limit query parameters of the
+// deliveries of a webhook.
+func parseDeliveryFilter(c echo.Context) (webhooks.DeliveryFilter, error) {
+	filter := webhooks.DeliveryFilter{Status: c.QueryParam("status")}
+	switch filter.Status {
+	case "", webhooks.StatusPending, webhooks.StatusDelivered, webhooks.StatusDead:
+	default:
+		return filter, errors.New("status must be 'pending', 'delivered' or 'dead'")
+	}
+
+	var err error
+	if filter.Limit, err = intParam(c, "limit", defaultPageSize); err != nil {
+		return filter, err
+	}
+	if filter.Limit < 1 || filter.Limit > maxPageSize {
+		return filter, errors.New("limit must be between 1 and 500")
+	}
+	if value := c.QueryParam("before"); value != "" {
+		if filter.Before, err = strconv.ParseInt(value, 10, 64); err != nil || filter.Before < 1 {
+			return filter, errors.New("invalid before parameter")
+		}
+	}
+	return filter, nil
+}
+
+func webhookID(c echo.Context) (int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, badRequest("Invalid webhook ID")
+	}
+	return id, nil
+}
+
+func deliveryIDs(c echo.Context) (int, int64, error) {
+	id, err := webhookID(c)
+	if err != nil {
+		return 0, 0, err
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		return 0, 0, badRequest("Invalid delivery ID")
+	}
+	return id, deliveryID, nil
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"codelit/db"
+	"codelit/internal/migrations"
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"codelit/internal/webhooks"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// seededWebhooks returns a server with webhooks kept in SQLite:
+//
+//	1 https://hr.example.com/hooks, member.created and member.deleted, with the
+//	  deliveries of event 1 (delivered), 2 (dead) and 3 (pending)
+//	2 https://payroll.example.com/hooks, member.updated, inactive
+func seededWebhooks(t *testing.T) *echo.Echo {
+	files, err := db.Migrations("sqlite")
+	require.NoError(t, err)
+	conn, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "members.db"))
+	require.NoError(t, err)
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	runner, err := migrations.NewRunner(conn, files, migrations.WithoutLock())
+	require.NoError(t, err)
+	_, err = runner.Up(context.Background())
+	require.NoError(t, err)
+
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := webhooks.NewStore(conn, webhooks.WithClock(func() time.Time { return now }))
+	for _, w := range []*webhooks.Webhook{
+		{URL: "https://hr.example.com/hooks", Secret: "hr-secret-0123456789", EventTypes: []string{outbox.MemberCreated, outbox.MemberDeleted}, Active: true},
+		{URL: "https://payroll.example.com/hooks", Secret: "payroll-secret-0123456789", EventTypes: []string{outbox.MemberUpdated}},
+	} {
+		require.NoError(t, store.Create(context.Background(), w))
+	}
+	for id, member := range []*models.Member{
+		{ID: 2, Name: "Bob", Type: models.MemberTypeContractor, Duration: 6, Version: 1},
+		{ID: 3, Name: "Carol", Type: models.MemberTypeEmployee, Role: "Manager", Version: 1},
+		{ID: 4, Name: "Dave", Type: models.MemberTypeContractor, Duration: 3, Version: 1},
+	} {
+		eventType := outbox.MemberCreated
+		if member.Name == "Carol" {
+			eventType = outbox.MemberDeleted
+		}
+		payload, err := json.Marshal(member)
+		require.NoError(t, err)
+		event := outbox.Event{ID: int64(id + 1), Type: eventType, MemberID: member.ID, Payload: payload}
+		require.NoError(t, store.HandleEvent(context.Background(), event))
+	}
+	attempted := now.Add(time.Second)
+	for _, statement := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{"UPDATE webhook_deliveries SET status = $1, attempts = 1, last_status_code = 200, delivered_at = $2 WHERE id = 1",
+			[]interface{}{webhooks.StatusDelivered, attempted}},
+		{"INSERT INTO webhook_attempts (delivery_id, attempt, status_code, duration_ms, attempted_at) VALUES (1, 1, 200, 42, $1)",
+			[]interface{}{attempted}},
+		{"UPDATE webhook_deliveries SET status = $1, attempts = 2, last_status_code = 500, last_error = $2 WHERE id = 2",
+			[]interface{}{webhooks.StatusDead, "webhook answered 500 Internal Server Error"}},
+		{"INSERT INTO webhook_attempts (delivery_id, attempt, error, duration_ms, attempted_at) VALUES (2, 1, $1, 1000, $2)",
+			[]interface{}{"connection refused", attempted}},
+		{"INSERT INTO webhook_attempts (delivery_id, attempt, status_code, error, duration_ms, attempted_at) VALUES (2, 2, 500, $1, 12, $2)",
+			[]interface{}{"webhook answered 500 Internal Server Error", attempted.Add(time.Minute)}},
+	} {
+		_, err := conn.Exec(statement.query, statement.args...)
+		require.NoError(t, err)
+	}
+
+	e := echo.New()
+	RegisterRoutes(e, seededRepository(t), WithWebhooks(store))
+	return e
+}
+
+func runWebhookTests(t *testing.T, tests []routeTest) {
+	runRouteTestsOn(t, seededWebhooks, tests)
+}
+
+func TestGetWebhooks(t *testing.T) {
+	runWebhookTests(t, []routeTest{
+		{
+			name: "all", method: http.MethodGet, target: "/webhooks",
+			status: http.StatusOK,
+		},
+		{
+			name: "by id", method: http.MethodGet, target: "/webhooks/2",
+			status: http.StatusOK,
+		},
+		{
+			name: "missing", method: http.MethodGet, target: "/webhooks/42",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "invalid id", method: http.MethodGet, target: "/webhooks/abc",
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestCreateWebhook(t *testing.T) {
+	runWebhookTests(t, []routeTest{
+		{
+			name: "created", method: http.MethodPost, target: "/webhooks",
+			body:   `{"url": "https://audit.example.com/hooks", "secret": "audit-secret-0123456789", "event_types": ["member.created"]}`,
+			status: http.StatusCreated,
+		},
+		{
+			name: "inactive", method: http.MethodPost, target: "/webhooks",
+			body:   `{"url": "https://audit.example.com/hooks", "secret": "audit-secret-0123456789", "event_types": ["member.deleted"], "active": false}`,
+			status: http.StatusCreated,
+		},
+		{
+			name: "invalid webhook", method: http.MethodPost, target: "/webhooks",
+			body:   `{"url": "audit.example.com", "secret": "short", "event_types": ["member.renamed"]}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "private address", method: http.MethodPost, target: "/webhooks",
+			body:   `{"url": "http://169.254.169.254/latest/meta-data", "event_types": ["member.created"]}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "invalid json", method: http.MethodPost, target: "/webhooks",
+			body:   `["https://audit.example.com/hooks"]`,
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	runWebhookTests(t, []routeTest{
+		{
+			name: "updated", method: http.MethodPut, target: "/webhooks/2",
+			body:   `{"url": "https://payroll.example.com/v2/hooks", "event_types": ["member.created", "member.updated"]}`,
+			status: http.StatusOK,
+		},
+		{
+			name: "missing", method: http.MethodPut, target: "/webhooks/42",
+			body:   `{"url": "https://payroll.example.com/hooks", "event_types": ["member.created"]}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name: "invalid webhook", method: http.MethodPut, target: "/webhooks/2",
+			body:   `{"url": "https://payroll.example.com/hooks", "event_types": []}`,
+			status: http.StatusUnprocessableEntity,
+		},
+	})
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	runWebhookTests(t, []routeTest{
+		{
+			name: "deleted", method: http.MethodDelete, target: "/webhooks/1",
+			status: http.StatusNoContent,
+		},
+		{
+			name: "missing", method: http.MethodDelete, target: "/webhooks/42",
+			status: http.StatusNotFound,
+		},
+	})
+}
+
+func TestGetWebhookDeliveries(t *testing.T) {
+	runWebhookTests(t, []routeTest{
+		{
+			name: "all", method: http.MethodGet, target: "/webhooks/1/deliveries",
+			status: http.StatusOK,
+		},
+		{
+			name: "dead", method: http.MethodGet, target: "/webhooks/1/deliveries?status=dead",
+			status: http.StatusOK,
+		},
+		{
+			name: "first page", method: http.MethodGet, target: "/webhooks/1/deliveries?limit=2",
+			status: http.StatusOK,
+		},
+		{
+			name: "next page", method: http.MethodGet, target: "/webhooks/1/deliveries?limit=2&before=2",
+			status: http.StatusOK,
+		},
+		{
+			name: "unknown status", method: http.MethodGet, target: "/webhooks/1/deliveries?status=failed",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "limit too large", method: http.MethodGet, target: "/webhooks/1/deliveries?limit=501",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "missing webhook", method: http.MethodGet, target: "/webhooks/42/deliveries",
+			status: http.StatusNotFound,
+		},
+	})
+}
+
+func TestGetWebhookDelivery(t *testing.T) {
+	runWebhookTests(t, []routeTest{
+		{
+			name: "with log", method: http.MethodGet, target: "/webhooks/1/deliveries/2",
+			status: http.StatusOK,
+		},
+		{
+			name: "other webhook", method: http.MethodGet, target: "/webhooks/2/deliveries/2",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "invalid id", method: http.MethodGet, target: "/webhooks/1/deliveries/abc",
+			status: http.StatusBadRequest,
+		},
+	})
+}
+
+func TestReplayWebhook(t *testing.T) {
+	runWebhookTests(t, []routeTest{
+		{
+			name: "delivery", method: http.MethodPost, target: "/webhooks/1/deliveries/1/replay",
+			status: http.StatusAccepted,
+		},
+		{
+			name: "missing delivery", method: http.MethodPost, target: "/webhooks/1/deliveries/42/replay",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "dead deliveries", method: http.MethodPost, target: "/webhooks/1/replay",
+			status: http.StatusAccepted,
+		},
+		{
+			name: "missing webhook", method: http.MethodPost, target: "/webhooks/42/replay",
+			status: http.StatusNotFound,
+		},
+	})
+}
+
+func TestWebhooksWithoutStore(t *testing.T) {
+	runRouteTests(t, []routeTest{
+		{
+			name: "not implemented", method: http.MethodGet, target: "/webhooks",
+			status: http.StatusNotImplemented,
+		},
+		{
+			name: "deliveries not implemented", method: http.MethodGet, target: "/webhooks/1/deliveries",
+			status: http.StatusNotImplemented,
+		},
+	})
+}
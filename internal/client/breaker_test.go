@@ -0,0 +1,47 @@
+package grpcclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	// Act & Assert
+	assert.True(t, b.allow())
+	b.record(false)
+	assert.True(t, b.allow(), "closed below the threshold")
+	b.record(false)
+	assert.False(t, b.allow(), "open at the threshold")
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.allow(), "probe after the cooldown")
+	assert.False(t, b.allow(), "a single probe at a time")
+	b.record(false)
+	assert.False(t, b.allow(), "open again after a failed probe")
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.allow())
+	b.record(true)
+	assert.True(t, b.allow(), "closed after a successful probe")
+	assert.True(t, b.allow())
+}
+
+func TestDisabledBreaker(t *testing.T) {
+	// Arrange
+	b := newBreaker(0, time.Minute)
+
+	// Act
+	for i := 0; i < 10; i++ {
+		b.record(false)
+	}
+
+	// Assert
+	assert.True(t, b.allow())
+}
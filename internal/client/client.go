@@ -0,0 +1,176 @@
+package grpcclient
+
+import (
+	"codelit/internal/client/pb"
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// ErrCircuitOpen is returned without calling the service while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("validation service circuit breaker is open")
+
+// Client calls the validation service on a single long-lived connection. Every
+// attempt has its own deadline, failed attempts are retried with exponential
+// backoff and jitter, and a circuit breaker stops calling the service for a
+// while after consecutive failed calls. It is safe for concurrent use.
+type Client struct {
+	conn        *grpc.ClientConn
+	validator   pb.MemberValidatorClient
+	dialOptions []grpc.DialOption
+
+	timeout        time.Duration
+	retries        int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	breaker        *breaker
+}
+
+type Option func(*Client)
+
+// WithTimeout sets the deadline of each attempt.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetries sets how many times a failed attempt is retried.
+func WithRetries(retries int) Option {
+	return func(c *Client) {
+		c.retries = retries
+	}
+}
+
+// WithBackoff sets the wait before the first retry, which doubles after every
+// retry up to max. The actual wait is a random duration up to that value.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(c *Client) {
+		c.initialBackoff = initial
+		c.maxBackoff = max
+	}
+}
+
+// WithBreaker opens the circuit after the given number of consecutive failed
+// calls. Once the cooldown has passed, a single call is let through to probe the
+// service. A zero threshold disables the breaker.
+func WithBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		c.breaker = newBreaker(threshold, cooldown)
+	}
+}
+
+// WithDialOptions adds options used to dial the service.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(c *Client) {
+		c.dialOptions = append(c.dialOptions, opts...)
+	}
+}
+
+// New returns a client of the service at target. The connection is established
+// in the background, so New does not fail when the service is down.
+func New(target string, opts ...Option) (*Client, error) {
+	c := &Client{
+		dialOptions:    []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())},
+		timeout:        2 * time.Second,
+		retries:        3,
+		initialBackoff: 100 * time.Millisecond,
+		maxBackoff:     2 * time.Second,
+		breaker:        newBreaker(5, 30*time.Second),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	conn, err := grpc.Dial(target, c.dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	c.validator = pb.NewMemberValidatorClient(conn)
+	return c, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// call runs rpc through the circuit breaker, retrying the attempts that failed
+// with a transient error.
+func (c *Client) call(ctx context.Context, rpc func(ctx context.Context) error) error {
+	if !c.breaker.allow() {
+		return ErrCircuitOpen
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err = rpc(attemptCtx)
+		cancel()
+		if err == nil || !retryable(err) || attempt >= c.retries {
+			break
+		}
+		if !sleep(ctx, c.backoff(attempt)) {
+			break
+		}
+	}
+
+	// Rejected requests say nothing about the health of the service
+	c.breaker.record(err == nil || rejected(err))
+	return err
+}
+
+// backoff returns the wait before the retry following the given attempt, using
+// "full jitter" so that clients do not retry in step.
+func (c *Client) backoff(attempt int) time.Duration {
+	backoff := c.initialBackoff
+	for i := 0; i < attempt && backoff < c.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > c.maxBackoff {
+		backoff = c.maxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+// retryable tells whether a failed attempt may succeed when tried again.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// rejected tells whether a call failed because of the request rather than the
+// service, in which case it does not count against the breaker.
+func rejected(err error) bool {
+	switch status.Code(err) {
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.FailedPrecondition, codes.OutOfRange:
+		return true
+	}
+	return false
+}
+
+// sleep waits for d unless ctx is done first, in which case it returns false.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
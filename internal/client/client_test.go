@@ -0,0 +1,139 @@
+package grpcclient
+
+import (
+	"codelit/internal/client/pb"
+	"codelit/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var valid = &pb.ValidateMemberResponse{Verdict: pb.Verdict_VERDICT_VALID}
+
+func TestRetriesTransientFailures(t *testing.T) {
+	// Arrange
+	stub := &stubValidator{failures: 2, code: codes.Unavailable, res: valid}
+	client := newStubClient(t, stub, WithRetries(2))
+
+	// Act
+	verdict, err := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, verdict.Valid)
+	assert.Equal(t, 3, stub.calls)
+}
+
+func TestGivesUpAfterRetries(t *testing.T) {
+	// Arrange
+	stub := &stubValidator{failures: 10, code: codes.Unavailable, res: valid}
+	client := newStubClient(t, stub, WithRetries(2))
+
+	// Act
+	_, err := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+
+	// Assert
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 3, stub.calls)
+}
+
+func TestDoesNotRetryRejectedRequests(t *testing.T) {
+	// Arrange
+	stub := &stubValidator{failures: 1, code: codes.InvalidArgument, res: valid}
+	client := newStubClient(t, stub, WithRetries(2))
+
+	// Act
+	_, err := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+
+	// Assert
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, 1, stub.calls)
+}
+
+func TestStopsRetryingWhenTheContextIsDone(t *testing.T) {
+	// Arrange
+	stub := &stubValidator{failures: 10, code: codes.Unavailable, res: valid}
+	client := newStubClient(t, stub, WithRetries(5), WithBackoff(time.Hour, time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Act
+	_, err := client.ValidateMember(ctx, &models.Member{Name: "Alice"})
+
+	// Assert
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, stub.calls)
+}
+
+func TestBreakerOpensAfterFailedCalls(t *testing.T) {
+	// Arrange
+	stub := &stubValidator{failures: 2, code: codes.Unavailable, res: valid}
+	client := newStubClient(t, stub, WithRetries(0), WithBreaker(2, time.Minute))
+	now := time.Now()
+	client.breaker.now = func() time.Time { return now }
+
+	// Act
+	_, first := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+	_, second := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+	_, whileOpen := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+	now = now.Add(time.Minute)
+	verdict, probe := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+
+	// Assert
+	assert.Error(t, first)
+	assert.Error(t, second)
+	assert.ErrorIs(t, whileOpen, ErrCircuitOpen)
+	assert.NoError(t, probe)
+	assert.True(t, verdict.Valid)
+	assert.Equal(t, 3, stub.calls)
+}
+
+func TestBreakerCountsServerErrors(t *testing.T) {
+	// Arrange
+	stub := &stubValidator{failures: 10, code: codes.Internal, res: valid}
+	client := newStubClient(t, stub, WithRetries(2), WithBreaker(2, time.Minute))
+
+	// Act
+	_, first := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+	_, second := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+	_, whileOpen := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+
+	// Assert
+	assert.Equal(t, codes.Internal, status.Code(first))
+	assert.Equal(t, codes.Internal, status.Code(second))
+	assert.ErrorIs(t, whileOpen, ErrCircuitOpen)
+	assert.Equal(t, 2, stub.calls, "server errors are not retried")
+}
+
+func TestBreakerIgnoresRejectedRequests(t *testing.T) {
+	// Arrange
+	stub := &stubValidator{failures: 10, code: codes.InvalidArgument, res: valid}
+	client := newStubClient(t, stub, WithRetries(0), WithBreaker(2, time.Minute))
+
+	// Act
+	for i := 0; i < 3; i++ {
+		_, err := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	// Assert
+	assert.Equal(t, 3, stub.calls)
+}
+
+func TestBackoffGrowsUpToTheMaximum(t *testing.T) {
+	// Arrange
+	client := &Client{initialBackoff: 10 * time.Millisecond, maxBackoff: 50 * time.Millisecond}
+
+	for attempt, limit := range []time.Duration{10, 20, 40, 50, 50} {
+		// Act
+		backoff := client.backoff(attempt)
+
+		// Assert
+		assert.LessOrEqual(t, backoff, limit*time.Millisecond)
+		assert.GreaterOrEqual(t, backoff, time.Duration(0))
+	}
+}
@@ -1,19 +1,61 @@
 package grpcclient
 
 import (
-	"log"
-	"os"
-
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"codelit/internal/client/pb"
+	"codelit/internal/models"
+	"context"
+	"fmt"
 )
 
-func StartGRPC() *grpc.ClientConn {
-	hostname := os.Getenv("DOCKER_INTERNAL")
-	conn, err := grpc.Dial(hostname+":9000", grpc.WithTransportCredentials(insecure.NewCredentials()))
+// ValidateMember sends the member to the validation service and returns its verdict.
+func (c *Client) ValidateMember(ctx context.Context, member *models.Member) (*models.Verdict, error) {
+	req := &pb.ValidateMemberRequest{Member: memberMessage(member)}
+	var res *pb.ValidateMemberResponse
+	err := c.call(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = c.validator.ValidateMember(ctx, req)
+		return err
+	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	return verdict(res)
+}
+
+func memberMessage(member *models.Member) *pb.Member {
+	memberType := pb.MemberType_MEMBER_TYPE_UNSPECIFIED
+	switch member.Type {
+	case models.MemberTypeContractor:
+		memberType = pb.MemberType_MEMBER_TYPE_CONTRACTOR
+	case models.MemberTypeEmployee:
+		memberType = pb.MemberType_MEMBER_TYPE_EMPLOYEE
 	}
+	return &pb.Member{
+		Id:       int64(member.ID),
+		Name:     member.Name,
+		Type:     memberType,
+		Role:     member.Role,
+		Duration: int32(member.Duration),
+		Tags:     member.Tags,
+		Version:  int64(member.Version),
+	}
+}
 
-	return conn
+func verdict(res *pb.ValidateMemberResponse) (*models.Verdict, error) {
+	v := &models.Verdict{Reasons: models.ValidationErrors{}}
+	switch res.GetVerdict() {
+	case pb.Verdict_VERDICT_VALID:
+		v.Valid = true
+	case pb.Verdict_VERDICT_REJECTED:
+	default:
+		return nil, fmt.Errorf("the validation service returned no verdict")
+	}
+	for _, reason := range res.GetReasons() {
+		v.Reasons = append(v.Reasons, models.FieldError{
+			Field:   reason.GetField(),
+			Code:    reason.GetCode(),
+			Message: reason.GetMessage(),
+		})
+	}
+	return v, nil
 }
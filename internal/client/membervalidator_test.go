@@ -0,0 +1,110 @@
+package grpcclient
+
+import (
+	"codelit/internal/client/pb"
+	"codelit/internal/models"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// stubValidator records the last request it gets and answers with res, after
+// failing the first failures calls with code.
+type stubValidator struct {
+	pb.UnimplementedMemberValidatorServer
+	mu       sync.Mutex
+	calls    int
+	failures int
+	code     codes.Code
+	req      *pb.ValidateMemberRequest
+	res      *pb.ValidateMemberResponse
+}
+
+func (s *stubValidator) ValidateMember(ctx context.Context, req *pb.ValidateMemberRequest) (*pb.ValidateMemberResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	s.req = req
+	if s.calls <= s.failures {
+		return nil, status.Error(s.code, "stub failure")
+	}
+	return s.res, nil
+}
+
+// newStubClient returns a client of the stub served in memory, with short
+// backoffs on top of the default options.
+func newStubClient(t *testing.T, stub *stubValidator, opts ...Option) *Client {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pb.RegisterMemberValidatorServer(server, stub)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	})
+	opts = append([]Option{WithDialOptions(dialer), WithBackoff(time.Millisecond, 4*time.Millisecond)}, opts...)
+	client, err := New("bufnet", opts...)
+	assert.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestValidateMemberSendsTheMember(t *testing.T) {
+	// Arrange
+	stub := &stubValidator{res: &pb.ValidateMemberResponse{Verdict: pb.Verdict_VERDICT_VALID}}
+	client := newStubClient(t, stub)
+	member := &models.Member{ID: 3, Name: "Bob", Type: models.MemberTypeContractor, Duration: 6, Tags: []string{"go"}, Version: 2}
+
+	// Act
+	verdict, err := client.ValidateMember(context.Background(), member)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, verdict.Valid)
+	assert.Empty(t, verdict.Reasons)
+	assert.Equal(t, int64(3), stub.req.GetMember().GetId())
+	assert.Equal(t, "Bob", stub.req.GetMember().GetName())
+	assert.Equal(t, pb.MemberType_MEMBER_TYPE_CONTRACTOR, stub.req.GetMember().GetType())
+	assert.Equal(t, int32(6), stub.req.GetMember().GetDuration())
+	assert.Equal(t, []string{"go"}, stub.req.GetMember().GetTags())
+	assert.Equal(t, int64(2), stub.req.GetMember().GetVersion())
+}
+
+func TestValidateMemberRejected(t *testing.T) {
+	// Arrange
+	stub := &stubValidator{res: &pb.ValidateMemberResponse{
+		Verdict: pb.Verdict_VERDICT_REJECTED,
+		Reasons: []*pb.Reason{{Field: "role", Code: "invalid", Message: "Unknown role"}},
+	}}
+	client := newStubClient(t, stub)
+
+	// Act
+	verdict, err := client.ValidateMember(context.Background(), &models.Member{Name: "Alice", Type: models.MemberTypeEmployee, Role: "Wizard"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.False(t, verdict.Valid)
+	assert.Equal(t, models.ValidationErrors{{Field: "role", Code: "invalid", Message: "Unknown role"}}, verdict.Reasons)
+	assert.Equal(t, pb.MemberType_MEMBER_TYPE_EMPLOYEE, stub.req.GetMember().GetType())
+}
+
+func TestValidateMemberWithoutVerdict(t *testing.T) {
+	// Arrange
+	client := newStubClient(t, &stubValidator{res: &pb.ValidateMemberResponse{}})
+
+	// Act
+	_, err := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+
+	// Assert
+	assert.EqualError(t, err, "the validation service returned no verdict")
+}
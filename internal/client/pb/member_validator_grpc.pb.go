@@ -0,0 +1,111 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: proto/member_validator.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	MemberValidator_ValidateMember_FullMethodName = "/membervalidation.v1.MemberValidator/ValidateMember"
+)
+
+// MemberValidatorClient is the client API for MemberValidator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MemberValidatorClient interface {
+	// Decides whether the member is acceptable
+	ValidateMember(ctx context.Context, in *ValidateMemberRequest, opts ...grpc.CallOption) (*ValidateMemberResponse, error)
+}
+
+type memberValidatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMemberValidatorClient(cc grpc.ClientConnInterface) MemberValidatorClient {
+	return &memberValidatorClient{cc}
+}
+
+func (c *memberValidatorClient) ValidateMember(ctx context.Context, in *ValidateMemberRequest, opts ...grpc.CallOption) (*ValidateMemberResponse, error) {
+	out := new(ValidateMemberResponse)
+	err := c.cc.Invoke(ctx, MemberValidator_ValidateMember_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MemberValidatorServer is the server API for MemberValidator service.
+// All implementations must embed UnimplementedMemberValidatorServer
+// for forward compatibility
+type MemberValidatorServer interface {
+	// Decides whether the member is acceptable
+	ValidateMember(context.Context, *ValidateMemberRequest) (*ValidateMemberResponse, error)
+	mustEmbedUnimplementedMemberValidatorServer()
+}
+
+// UnimplementedMemberValidatorServer must be embedded to have forward compatible implementations.
+type UnimplementedMemberValidatorServer struct {
+}
+
+func (UnimplementedMemberValidatorServer) ValidateMember(context.Context, *ValidateMemberRequest) (*ValidateMemberResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateMember not implemented")
+}
+func (UnimplementedMemberValidatorServer) mustEmbedUnimplementedMemberValidatorServer() {}
+
+// UnsafeMemberValidatorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MemberValidatorServer will
+// result in compilation errors.
+type UnsafeMemberValidatorServer interface {
+	mustEmbedUnimplementedMemberValidatorServer()
+}
+
+func RegisterMemberValidatorServer(s grpc.ServiceRegistrar, srv MemberValidatorServer) {
+	s.RegisterService(&MemberValidator_ServiceDesc, srv)
+}
+
+func _MemberValidator_ValidateMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateMemberRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemberValidatorServer).ValidateMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MemberValidator_ValidateMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemberValidatorServer).ValidateMember(ctx, req.(*ValidateMemberRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MemberValidator_ServiceDesc is the grpc.ServiceDesc for MemberValidator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MemberValidator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "membervalidation.v1.MemberValidator",
+	HandlerType: (*MemberValidatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ValidateMember",
+			Handler:    _MemberValidator_ValidateMember_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/member_validator.proto",
+}
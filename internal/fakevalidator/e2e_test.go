@@ -0,0 +1,137 @@
+package fakevalidator_test
+
+import (
+	"codelit/db"
+	grpcclient "codelit/internal/client"
+	"codelit/internal/fakevalidator"
+	"codelit/internal/migrations"
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"codelit/internal/repositories"
+	"codelit/internal/validation"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	_ "modernc.org/sqlite"
+)
+
+// pipeline is the asynchronous validation path of the API: members stored in
+// SQLite, their events relayed to the dispatcher, which asks the fake service.
+type pipeline struct {
+	repo    *repositories.DBRepository
+	relay   *outbox.Relay
+	service *fakevalidator.Server
+}
+
+func newPipeline(t *testing.T) *pipeline {
+	files, err := db.Migrations("sqlite")
+	require.NoError(t, err)
+	conn, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "members.db"))
+	require.NoError(t, err)
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+	runner, err := migrations.NewRunner(conn, files, migrations.WithoutLock())
+	require.NoError(t, err)
+	_, err = runner.Up(context.Background())
+	require.NoError(t, err)
+
+	service := fakevalidator.New(fakevalidator.Config{})
+	dialer, stop := service.InProcess()
+	t.Cleanup(stop)
+	client, err := grpcclient.New("bufnet", grpcclient.WithDialOptions(dialer),
+		grpcclient.WithRetries(1), grpcclient.WithBackoff(time.Millisecond, time.Millisecond))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	repo := repositories.NewSQLiteRepository(conn)
+	dispatcher := validation.NewDispatcher(client)
+	dispatcher.Start(repo)
+	t.Cleanup(func() { dispatcher.Shutdown(context.Background()) })
+
+	relay := outbox.NewRelay(conn, outbox.WithoutRowLocks(), outbox.WithBackoff(0, 0))
+	relay.Handle(outbox.MemberCreated, dispatcher)
+	relay.Handle(outbox.MemberUpdated, dispatcher)
+	relay.Handle(outbox.MemberRevalidated, dispatcher)
+	return &pipeline{repo: repo, relay: relay, service: service}
+}
+
+func (p *pipeline) status(t *testing.T, id int) *models.Member {
+	t.Helper()
+	member, err := p.repo.GetMemberByID(context.Background(), id)
+	require.NoError(t, err)
+	return member
+}
+
+func TestMembersAreValidatedEndToEnd(t *testing.T) {
+	// Arrange
+	p := newPipeline(t)
+	p.service.Respond("Mallory", fakevalidator.Response{
+		Verdict: "rejected",
+		Reasons: []fakevalidator.Reason{{Field: "name", Code: models.CodeForbidden, Message: "Mallory is not welcome"}},
+	})
+	alice := &models.Member{Name: "Alice", Type: models.MemberTypeEmployee, Role: "Engineer"}
+	mallory := &models.Member{Name: "Mallory", Type: models.MemberTypeContractor, Duration: 3}
+	require.NoError(t, p.repo.CreateMember(context.Background(), alice))
+	require.NoError(t, p.repo.CreateMember(context.Background(), mallory))
+
+	// Act
+	_, err := p.relay.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, models.ValidationValid, p.status(t, alice.ID).ValidationStatus)
+	rejected := p.status(t, mallory.ID)
+	assert.Equal(t, models.ValidationRejected, rejected.ValidationStatus)
+	assert.Equal(t, "Mallory is not welcome", rejected.ValidationReason)
+}
+
+func TestUpdatesAreValidatedAgain(t *testing.T) {
+	// Arrange
+	p := newPipeline(t)
+	p.service.Respond("Mallory", fakevalidator.Response{
+		Verdict: "rejected",
+		Reasons: []fakevalidator.Reason{{Field: "name", Code: models.CodeForbidden, Message: "Mallory is not welcome"}},
+	})
+	member := &models.Member{Name: "Alice", Type: models.MemberTypeEmployee, Role: "Engineer"}
+	require.NoError(t, p.repo.CreateMember(context.Background(), member))
+	member.Name = "Mallory"
+	require.NoError(t, p.repo.UpdateMember(context.Background(), member))
+
+	// Act
+	_, err := p.relay.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	rejected := p.status(t, member.ID)
+	assert.Equal(t, models.ValidationRejected, rejected.ValidationStatus, "the verdict on the created member is stale")
+	assert.Equal(t, "Mallory is not welcome", rejected.ValidationReason)
+	assert.Equal(t, member.Version, rejected.Version, "verdicts do not change the version")
+}
+
+func TestUnavailableServiceIsRevalidated(t *testing.T) {
+	// Arrange
+	p := newPipeline(t)
+	alice := &models.Member{Name: "Alice", Type: models.MemberTypeEmployee, Role: "Engineer"}
+	require.NoError(t, p.repo.CreateMember(context.Background(), alice))
+	p.service.FailNext(2, codes.Unavailable)
+
+	// Act
+	_, err := p.relay.Poll(context.Background())
+	failed := p.status(t, alice.ID)
+	_, revalidateErr := p.repo.RevalidateMember(context.Background(), alice.ID)
+	require.NoError(t, revalidateErr)
+	_, retryErr := p.relay.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	require.NoError(t, retryErr)
+	assert.Equal(t, models.ValidationError, failed.ValidationStatus)
+	assert.Equal(t, models.ValidationValid, p.status(t, alice.ID).ValidationStatus)
+	assert.Len(t, p.service.Requests(), 3)
+}
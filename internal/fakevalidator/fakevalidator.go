@@ -0,0 +1,235 @@
+// Package fakevalidator is a fake of the member validation service, for local
+// development and tests. It answers with canned verdicts, and can be slowed down
+// or made to fail to exercise the retries of the client.
+package fakevalidator
+
+import (
+	"bytes"
+	"codelit/internal/client/pb"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gopkg.in/yaml.v3"
+)
+
+// Response is a canned answer to a validation request.
+type Response struct {
+	// Verdict is valid or rejected, valid when empty
+	Verdict string   `yaml:"verdict"`
+	Reasons []Reason `yaml:"reasons"`
+	// Latency delays the answer
+	Latency time.Duration `yaml:"latency"`
+	// Error fails the request with the named gRPC code, such as Unavailable
+	Error string `yaml:"error"`
+}
+
+// Reason tells why a member is rejected.
+type Reason struct {
+	Field   string `yaml:"field"`
+	Code    string `yaml:"code"`
+	Message string `yaml:"message"`
+}
+
+// Config is the behaviour of the server, usually loaded from a YAML file:
+//
+//	default:
+//	  latency: 50ms
+//	members:
+//	  Mallory:
+//	    verdict: rejected
+//	    reasons:
+//	      - {field: name, code: forbidden, message: Mallory is not welcome}
+//	  Slow Sam:
+//	    latency: 5s
+//	failure_rate: 0.1
+type Config struct {
+	// Default answers the members without a response of their own
+	Default Response `yaml:"default"`
+	// Members holds the responses by member name
+	Members map[string]Response `yaml:"members"`
+	// FailureRate is the share of requests failing with Unavailable, from 0 to 1
+	FailureRate float64 `yaml:"failure_rate"`
+}
+
+// LoadConfig reads the configuration in the YAML file at path.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	config := Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("invalid fake validator configuration in %s: %w", path, err)
+	}
+	if err := config.check(); err != nil {
+		return Config{}, fmt.Errorf("invalid fake validator configuration in %s: %w", path, err)
+	}
+	return config, nil
+}
+
+func (c Config) check() error {
+	if c.FailureRate < 0 || c.FailureRate > 1 {
+		return fmt.Errorf("failure_rate must be between 0 and 1")
+	}
+	responses := map[string]Response{"default": c.Default}
+	for name, res := range c.Members {
+		responses[name] = res
+	}
+	for name, res := range responses {
+		if _, err := response(res); err != nil {
+			return fmt.Errorf("%s: %v", name, status.Convert(err).Message())
+		}
+		if res.Error != "" {
+			if _, err := parseCode(res.Error); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Server implements the MemberValidator service with canned responses. It is
+// safe for concurrent use.
+type Server struct {
+	pb.UnimplementedMemberValidatorServer
+
+	mu       sync.Mutex
+	config   Config
+	failures []codes.Code
+	requests []*pb.ValidateMemberRequest
+	random   *rand.Rand
+}
+
+func New(config Config) *Server {
+	return &Server{config: config, random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Respond sets the response to the member of the given name.
+func (s *Server) Respond(name string, res Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.Members == nil {
+		s.config.Members = map[string]Response{}
+	}
+	s.config.Members[name] = res
+}
+
+// FailNext fails the next n requests with code, whoever they are about.
+func (s *Server) FailNext(n int, code codes.Code) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, code)
+	}
+}
+
+// Requests returns the requests received so far, failed ones included.
+func (s *Server) Requests() []*pb.ValidateMemberRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*pb.ValidateMemberRequest(nil), s.requests...)
+}
+
+func (s *Server) ValidateMember(ctx context.Context, req *pb.ValidateMemberRequest) (*pb.ValidateMemberResponse, error) {
+	res, failure := s.next(req)
+
+	if res.Latency > 0 {
+		timer := time.NewTimer(res.Latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+		}
+	}
+	if failure != codes.OK {
+		return nil, status.Error(failure, "injected failure")
+	}
+	return response(res)
+}
+
+// next records the request and returns its response, and the code it must fail
+// with if any.
+func (s *Server) next(req *pb.ValidateMemberRequest) (Response, codes.Code) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, req)
+	res, ok := s.config.Members[req.GetMember().GetName()]
+	if !ok {
+		res = s.config.Default
+	}
+
+	failure := codes.OK
+	switch {
+	case len(s.failures) > 0:
+		failure, s.failures = s.failures[0], s.failures[1:]
+	case res.Error != "":
+		failure, _ = parseCode(res.Error)
+	case s.config.FailureRate > 0 && s.random.Float64() < s.config.FailureRate:
+		failure = codes.Unavailable
+	}
+	return res, failure
+}
+
+func response(res Response) (*pb.ValidateMemberResponse, error) {
+	out := &pb.ValidateMemberResponse{}
+	switch res.Verdict {
+	case "", "valid":
+		out.Verdict = pb.Verdict_VERDICT_VALID
+	case "rejected":
+		out.Verdict = pb.Verdict_VERDICT_REJECTED
+	default:
+		return nil, status.Errorf(codes.Internal, "unknown canned verdict %q, use valid or rejected", res.Verdict)
+	}
+	for _, reason := range res.Reasons {
+		out.Reasons = append(out.Reasons, &pb.Reason{Field: reason.Field, Code: reason.Code, Message: reason.Message})
+	}
+	return out, nil
+}
+
+// parseCode returns the gRPC code of the given name, such as DeadlineExceeded.
+func parseCode(name string) (codes.Code, error) {
+	for code := codes.OK; code <= codes.Unauthenticated; code++ {
+		if code.String() == name {
+			return code, nil
+		}
+	}
+	return codes.Unknown, fmt.Errorf("unknown gRPC code %q", name)
+}
+
+// Serve answers the requests accepted on listener until Stop is called on the
+// returned server.
+func (s *Server) Serve(listener net.Listener) *grpc.Server {
+	server := grpc.NewServer()
+	pb.RegisterMemberValidatorServer(server, s)
+	go server.Serve(listener)
+	return server
+}
+
+// InProcess serves s in memory and returns the dial option reaching it, to be
+// given to the client along with any target, and a function stopping it.
+func (s *Server) InProcess() (grpc.DialOption, func()) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := s.Serve(listener)
+	dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	})
+	return dialer, server.Stop
+}
@@ -0,0 +1,132 @@
+package fakevalidator
+
+import (
+	grpcclient "codelit/internal/client"
+	"codelit/internal/models"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newClient returns a client of the server served in memory.
+func newClient(t *testing.T, server *Server, opts ...grpcclient.Option) *grpcclient.Client {
+	dialer, stop := server.InProcess()
+	t.Cleanup(stop)
+	opts = append([]grpcclient.Option{grpcclient.WithDialOptions(dialer), grpcclient.WithBackoff(time.Millisecond, 4*time.Millisecond)}, opts...)
+	client, err := grpcclient.New("bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestServerAnswersWithCannedResponses(t *testing.T) {
+	// Arrange
+	server := New(Config{})
+	server.Respond("Mallory", Response{
+		Verdict: "rejected",
+		Reasons: []Reason{{Field: "name", Code: models.CodeForbidden, Message: "Mallory is not welcome"}},
+	})
+	client := newClient(t, server)
+
+	// Act
+	alice, aliceErr := client.ValidateMember(context.Background(), &models.Member{ID: 1, Name: "Alice"})
+	mallory, malloryErr := client.ValidateMember(context.Background(), &models.Member{ID: 2, Name: "Mallory"})
+
+	// Assert
+	require.NoError(t, aliceErr)
+	require.NoError(t, malloryErr)
+	assert.True(t, alice.Valid)
+	assert.False(t, mallory.Valid)
+	assert.Equal(t, models.ValidationErrors{{Field: "name", Code: models.CodeForbidden, Message: "Mallory is not welcome"}}, mallory.Reasons)
+	if requests := server.Requests(); assert.Len(t, requests, 2) {
+		assert.Equal(t, int64(2), requests[1].GetMember().GetId())
+	}
+}
+
+func TestServerInjectsFailures(t *testing.T) {
+	// Arrange
+	server := New(Config{Members: map[string]Response{"Bob": {Error: "PermissionDenied"}}})
+	server.FailNext(2, codes.Unavailable)
+	client := newClient(t, server, grpcclient.WithRetries(2))
+
+	// Act
+	_, retriedErr := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+	_, bobErr := client.ValidateMember(context.Background(), &models.Member{Name: "Bob"})
+
+	// Assert
+	assert.NoError(t, retriedErr)
+	assert.Equal(t, codes.PermissionDenied, status.Code(bobErr))
+	assert.Len(t, server.Requests(), 4)
+}
+
+func TestServerInjectsLatency(t *testing.T) {
+	// Arrange
+	server := New(Config{Default: Response{Latency: time.Minute}})
+	client := newClient(t, server, grpcclient.WithRetries(0), grpcclient.WithTimeout(20*time.Millisecond))
+
+	// Act
+	_, err := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+
+	// Assert
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestServerFailsAtTheFailureRate(t *testing.T) {
+	// Arrange
+	client := newClient(t, New(Config{FailureRate: 1}), grpcclient.WithRetries(0))
+
+	// Act
+	_, err := client.ValidateMember(context.Background(), &models.Member{Name: "Alice"})
+
+	// Assert
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestLoadConfig(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+	valid := write("valid.yaml", `
+default:
+  latency: 50ms
+members:
+  Mallory:
+    verdict: rejected
+    reasons:
+      - {field: name, code: forbidden, message: Mallory is not welcome}
+  Bob:
+    error: Unavailable
+failure_rate: 0.1
+`)
+	invalid := []string{
+		write("verdict.yaml", "members:\n  Bob:\n    verdict: maybe"),
+		write("code.yaml", "default:\n  error: Broken"),
+		write("rate.yaml", "failure_rate: 2"),
+		write("unknown.yaml", "delay: 1s"),
+	}
+
+	// Act
+	config, err := LoadConfig(valid)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 50*time.Millisecond, config.Default.Latency)
+	assert.Equal(t, "rejected", config.Members["Mallory"].Verdict)
+	assert.Equal(t, "Unavailable", config.Members["Bob"].Error)
+	assert.Equal(t, 0.1, config.FailureRate)
+	for _, path := range invalid {
+		_, err := LoadConfig(path)
+		assert.Error(t, err, filepath.Base(path))
+	}
+}
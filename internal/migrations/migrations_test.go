@@ -0,0 +1,123 @@
+package migrations
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testFiles = fstest.MapFS{
+	"0002_add_role.up.sql":        {Data: []byte("ALTER TABLE t ADD role TEXT")},
+	"0002_add_role.down.sql":      {Data: []byte("ALTER TABLE t DROP role")},
+	"0001_create_table.up.sql":    {Data: []byte("CREATE TABLE t (id INT)")},
+	"0001_create_table.down.sql":  {Data: []byte("DROP TABLE t")},
+	"0010_add_index.up.sql":       {Data: []byte("CREATE INDEX t_idx ON t (id)")},
+	"0010_add_index.down.sql":     {Data: []byte("DROP INDEX t_idx")},
+	"documentation/README.md":     {Data: []byte("not a migration")},
+	"documentation/0003_x.up.sql": {Data: []byte("not in the root")},
+}
+
+func TestLoad(t *testing.T) {
+	// Act
+	migrations, err := Load(testFiles)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "create_table", Up: "CREATE TABLE t (id INT)", Down: "DROP TABLE t"},
+		{Version: 2, Name: "add_role", Up: "ALTER TABLE t ADD role TEXT", Down: "ALTER TABLE t DROP role"},
+		{Version: 10, Name: "add_index", Up: "CREATE INDEX t_idx ON t (id)", Down: "DROP INDEX t_idx"},
+	}, migrations)
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	_, err := Load(fstest.MapFS{"create_table.up.sql": {Data: []byte("CREATE TABLE t (id INT)")}})
+
+	assert.EqualError(t, err, "migration create_table.up.sql must be named <version>_<name>")
+}
+
+func expectLock(mock sqlmock.Sqlmock, applied ...int64) {
+	mock.ExpectExec("SELECT pg_advisory_lock\\(\\$1\\)").WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version"})
+	for _, version := range applied {
+		rows.AddRow(version)
+	}
+	mock.ExpectQuery("SELECT version FROM schema_migrations").WillReturnRows(rows)
+}
+
+func TestUpAppliesPendingMigrations(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	runner, err := NewRunner(db, testFiles)
+	assert.NoError(t, err)
+
+	expectLock(mock, 1)
+	for _, migration := range runner.migrations[1:] {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(migration.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations \\(version, name\\) VALUES \\(\\$1, \\$2\\)").
+			WithArgs(migration.Version, migration.Name).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec("SELECT pg_advisory_unlock\\(\\$1\\)").WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Act
+	count, err := runner.Up(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDownRevertsLatestMigrations(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	runner, err := NewRunner(db, testFiles)
+	assert.NoError(t, err)
+
+	expectLock(mock, 1, 2)
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE t DROP role").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations WHERE version = \\$1").
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("SELECT pg_advisory_unlock\\(\\$1\\)").WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// Act
+	count, err := runner.Down(context.Background(), 1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpWithoutLock(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	runner, err := NewRunner(db, testFiles, WithoutLock())
+	assert.NoError(t, err)
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1).AddRow(2).AddRow(10))
+
+	// Act
+	count, err := runner.Up(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// Actions of the audit entries.
+const (
+	AuditCreated  = "created"
+	AuditUpdated  = "updated"
+	AuditDeleted  = "deleted"
+	AuditRestored = "restored"
+)
+
+// Actor is who changes members, as told by the client, and the request the
+// changes are made in.
+type Actor struct {
+	Name      string
+	RequestID string
+}
+
+// FieldChange is the value of a field before and after a change. A missing or
+// empty value is null.
+type FieldChange struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// AuditEntry records a change of a member. Entries are never modified once
+// written.
+type AuditEntry struct {
+	ID        int64  `json:"id"`
+	MemberID  int    `json:"member_id"`
+	Action    string `json:"action"`
+	Actor     string `json:"actor"`
+	RequestID string `json:"request_id,omitempty"`
+	// Version is the version of the member after the change, or the deleted
+	// version
+	Version int                    `json:"version"`
+	At      time.Time              `json:"at"`
+	Changes map[string]FieldChange `json:"changes"`
+}
+
+// AuditPage is a page of audit entries, the latest first. NextBefore is the
+// before parameter of the next page, zero on the last page.
+type AuditPage struct {
+	Entries    []*AuditEntry `json:"entries"`
+	NextBefore int64         `json:"next_before,omitempty"`
+}
+
+// Diff returns the fields that differ between two states of a member, nil
+// before it is created and after it is deleted. The fields managed by the
+// server are left out.
+func Diff(before, after *Member) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	for _, field := range []string{"name", "type", "role", "duration", "tags"} {
+		old, new := auditValue(before, field), auditValue(after, field)
+		if !reflect.DeepEqual(old, new) {
+			changes[field] = FieldChange{Before: rawValue(old), After: rawValue(new)}
+		}
+	}
+	return changes
+}
+
+// auditValue returns a field of the member, nil when it is empty.
+func auditValue(member *Member, field string) interface{} {
+	if member == nil {
+		return nil
+	}
+	switch field {
+	case "name":
+		if member.Name != "" {
+			return member.Name
+		}
+	case "type":
+		if member.Type != "" {
+			return member.Type
+		}
+	case "role":
+		if member.Role != "" {
+			return member.Role
+		}
+	case "duration":
+		if member.Duration != 0 {
+			return member.Duration
+		}
+	case "tags":
+		if len(member.Tags) > 0 {
+			return member.Tags
+		}
+	}
+	return nil
+}
+
+func rawValue(value interface{}) json.RawMessage {
+	// The values are strings, numbers or string slices, which always marshal
+	data, _ := json.Marshal(value)
+	return data
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	alice := &Member{ID: 1, Name: "Alice", Type: MemberTypeEmployee, Role: "Engineer", Tags: []string{"go"}, Version: 1}
+	promoted := &Member{ID: 1, Name: "Alice", Type: MemberTypeEmployee, Role: "Tech Lead", Tags: []string{}, Version: 2,
+		ValidationStatus: ValidationValid}
+
+	tests := []struct {
+		name          string
+		before, after *Member
+		want          string
+	}{
+		{"created", nil, alice,
+			`{"name":{"before":null,"after":"Alice"},"role":{"before":null,"after":"Engineer"},"tags":{"before":null,"after":["go"]},"type":{"before":null,"after":"employee"}}`},
+		{"updated", alice, promoted,
+			`{"role":{"before":"Engineer","after":"Tech Lead"},"tags":{"before":["go"],"after":null}}`},
+		{"unchanged", alice, alice, `{}`},
+		{"deleted", promoted, nil,
+			`{"name":{"before":"Alice","after":null},"role":{"before":"Tech Lead","after":null},"type":{"before":"employee","after":null}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes, err := json.Marshal(Diff(test.before, test.after))
+
+			require.NoError(t, err)
+			assert.JSONEq(t, test.want, string(changes))
+		})
+	}
+}
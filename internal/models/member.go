@@ -1,5 +1,16 @@
 package models
 
+import "time"
+
+// Validation statuses of a member. Members are pending until the validation
+// service gave its verdict, and in error when it could not be asked.
+const (
+	ValidationPending  = "pending"
+	ValidationValid    = "valid"
+	ValidationRejected = "rejected"
+	ValidationError    = "error"
+)
+
 type Member struct {
 	ID       int      `json:"id"`
 	Name     string   `json:"name"`
@@ -7,4 +18,45 @@ type Member struct {
 	Role     string   `json:"role,omitempty"`
 	Duration int      `json:"duration,omitempty"`
 	Tags     []string `json:"tags,omitempty"`
+	Version  int      `json:"version"`
+
+	// Set by the server only, they are ignored in requests
+	ValidationStatus string     `json:"validation_status,omitempty"`
+	ValidationReason string     `json:"validation_reason,omitempty"`
+	ValidatedAt      *time.Time `json:"validated_at,omitempty"`
+	// DeletedAt is only set on the deleted members listed with include_deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Validation is the outcome of validating a given version of a member.
+type Validation struct {
+	Status string
+	Reason string
+	At     time.Time
+}
+
+// IsValidationStatus tells whether status is one of the validation statuses.
+func IsValidationStatus(status string) bool {
+	switch status {
+	case ValidationPending, ValidationValid, ValidationRejected, ValidationError:
+		return true
+	}
+	return false
+}
+
+// MemberList is a single page of members along with the total number of
+// members matching the filters that produced it. NextCursor is empty on the
+// last page.
+type MemberList struct {
+	Members    []*Member `json:"members"`
+	Total      int       `json:"total"`
+	Limit      int       `json:"limit"`
+	Offset     int       `json:"offset"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// TagCount is a tag along with the number of members that have it.
+type TagCount struct {
+	Tag     string `json:"tag"`
+	Members int    `json:"members"`
 }
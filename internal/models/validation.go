@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	MemberTypeContractor = "contractor"
+	MemberTypeEmployee   = "employee"
+)
+
+// Validation error codes
+const (
+	CodeRequired  = "required"
+	CodeForbidden = "forbidden"
+	CodeInvalid   = "invalid"
+	CodeTooLong   = "too_long"
+	CodeDuplicate = "duplicate"
+)
+
+const maxTextLength = 255
+
+// MaxTagLength is the maximum length of a folded tag.
+const MaxTagLength = 64
+
+// FoldTag returns the canonical form of a tag: trimmed and lower case. Tags with
+// the same canonical form are the same tag.
+func FoldTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// FieldError describes one rule a member field violates.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors holds every rule violated by a member.
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Verdict is the decision of a validator on a member. Reasons tells why a member
+// was rejected.
+type Verdict struct {
+	Valid   bool
+	Reasons ValidationErrors
+}
+
+func (errs *ValidationErrors) add(field, code, message string) {
+	*errs = append(*errs, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Validate checks the member against the contractor and employee rules and returns
+// all violations at once as ValidationErrors, or nil when the member is valid.
+// Every code path that builds a member from outside input must call it.
+func (m *Member) Validate() error {
+	errs := ValidationErrors{}
+
+	if strings.TrimSpace(m.Name) == "" {
+		errs.add("name", CodeRequired, "Members must have a name")
+	} else if len(m.Name) > maxTextLength {
+		errs.add("name", CodeTooLong, fmt.Sprintf("Names must be at most %d characters", maxTextLength))
+	}
+
+	switch m.Type {
+	case MemberTypeContractor:
+		if m.Duration == 0 {
+			errs.add("duration", CodeRequired, "Contractors must have a duration")
+		} else if m.Duration < 0 {
+			errs.add("duration", CodeInvalid, "Durations must be positive")
+		}
+		if m.Role != "" {
+			errs.add("role", CodeForbidden, "Contractors must not have a role")
+		}
+	case MemberTypeEmployee:
+		if strings.TrimSpace(m.Role) == "" {
+			errs.add("role", CodeRequired, "Employees must have a role")
+		} else if len(m.Role) > maxTextLength {
+			errs.add("role", CodeTooLong, fmt.Sprintf("Roles must be at most %d characters", maxTextLength))
+		}
+		if m.Duration != 0 {
+			errs.add("duration", CodeForbidden, "Employees must not have a duration")
+		}
+	case "":
+		errs.add("type", CodeRequired, "Members must have a type, please use 'contractor' or 'employee'")
+	default:
+		errs.add("type", CodeInvalid, "Invalid member type, please use 'contractor' or 'employee'")
+	}
+
+	// Tags are compared and measured folded, as they are stored
+	seen := map[string]bool{}
+	for _, tag := range m.Tags {
+		folded := FoldTag(tag)
+		switch {
+		case folded == "":
+			errs.add("tags", CodeInvalid, "Tags must not be empty")
+		case len(folded) > MaxTagLength:
+			errs.add("tags", CodeTooLong, fmt.Sprintf("Tags must be at most %d characters", MaxTagLength))
+		case seen[folded]:
+			errs.add("tags", CodeDuplicate, fmt.Sprintf("Tag %q is repeated", folded))
+		}
+		seen[folded] = true
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAcceptsValidMembers(t *testing.T) {
+	members := []*Member{
+		{Name: "John Doe", Type: "employee", Role: "Software Engineer", Tags: []string{"go"}},
+		{Name: "Jane Smith", Type: "contractor", Duration: 6},
+	}
+
+	for _, member := range members {
+		assert.NoError(t, member.Validate())
+	}
+}
+
+func TestValidateReportsEveryViolation(t *testing.T) {
+	tests := []struct {
+		member *Member
+		want   ValidationErrors
+	}{
+		{
+			&Member{Type: "contractor", Role: "Software Engineer"},
+			ValidationErrors{
+				{"name", CodeRequired, "Members must have a name"},
+				{"duration", CodeRequired, "Contractors must have a duration"},
+				{"role", CodeForbidden, "Contractors must not have a role"},
+			},
+		},
+		{
+			&Member{Name: "John Doe", Type: "employee", Duration: 3},
+			ValidationErrors{
+				{"role", CodeRequired, "Employees must have a role"},
+				{"duration", CodeForbidden, "Employees must not have a duration"},
+			},
+		},
+		{
+			&Member{Name: "John Doe", Type: "intern", Tags: []string{"go", " ", "go"}},
+			ValidationErrors{
+				{"type", CodeInvalid, "Invalid member type, please use 'contractor' or 'employee'"},
+				{"tags", CodeInvalid, "Tags must not be empty"},
+				{"tags", CodeDuplicate, `Tag "go" is repeated`},
+			},
+		},
+		{
+			&Member{Name: "John Doe", Type: "contractor", Duration: 6, Tags: []string{"Go", " go ", strings.Repeat("a", MaxTagLength+1)}},
+			ValidationErrors{
+				{"tags", CodeDuplicate, `Tag "go" is repeated`},
+				{"tags", CodeTooLong, "Tags must be at most 64 characters"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		err := test.member.Validate()
+
+		assert.Equal(t, test.want, err)
+	}
+}
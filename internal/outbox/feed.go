@@ -0,0 +1,98 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// eventColumns are the columns scanned by scanEvents, in order.
+const eventColumns = "id, event_type, member_id, payload, created_at, attempts"
+
+// Feed reads the events of the outbox in the order they were written, whether
+// they were relayed or not, for the consumers following the changes of members.
+// Delivered events are kept for the retention period of the Relay, so that a
+// consumer can resume after the last event it saw within that period.
+//
+// Ids are assigned when events are written but only become visible when their
+// transaction commits, so an event may appear after a later one. The feed only
+// returns the events written longer ago than the settle delay, which must be
+// longer than the write transactions last: by then the transactions holding the
+// earlier ids are over and a consumer resuming after an id misses no event.
+type Feed struct {
+	db     *sql.DB
+	settle time.Duration
+	now    func() time.Time
+}
+
+type FeedOption func(*Feed)
+
+// WithSettleDelay sets how long after they were written events are returned,
+// 10 seconds by default. A zero delay suits databases such as SQLite where write
+// transactions commit one at a time, in the order of the ids.
+func WithSettleDelay(delay time.Duration) FeedOption {
+	return func(f *Feed) {
+		f.settle = delay
+	}
+}
+
+func NewFeed(db *sql.DB, opts ...FeedOption) *Feed {
+	f := &Feed{
+		db:     db,
+		settle: 10 * time.Second,
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Latest returns the id of the latest settled event, zero when there is none.
+func (f *Feed) Latest(ctx context.Context) (int64, error) {
+	var id int64
+	err := f.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM outbox WHERE created_at <= $1", f.settledBefore()).Scan(&id)
+	return id, err
+}
+
+// After returns up to limit settled events following the event of the given id.
+// It stops before the first event that did not settle yet, so that the events
+// committed meanwhile with a lower id are returned first.
+func (f *Feed) After(ctx context.Context, id int64, limit int) ([]Event, error) {
+	rows, err := f.db.QueryContext(ctx, "SELECT "+eventColumns+" FROM outbox WHERE id > $1 ORDER BY id LIMIT $2", id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	settledBefore := f.settledBefore()
+	for i, event := range events {
+		if event.CreatedAt.After(settledBefore) {
+			return events[:i], nil
+		}
+	}
+	return events, nil
+}
+
+// settledBefore returns the time the settled events were written before.
+func (f *Feed) settledBefore() time.Time {
+	return f.now().UTC().Add(-f.settle)
+}
+
+func scanEvents(rows *sql.Rows) ([]Event, error) {
+	events := []Event{}
+	for rows.Next() {
+		event := Event{}
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.Type, &event.MemberID, &payload, &event.CreatedAt, &event.Attempts); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,85 @@
+package outbox
+
+import (
+	"codelit/internal/models"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedReadsEventsInOrder(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	feed := NewFeed(conn, WithSettleDelay(0))
+	empty, err := feed.Latest(context.Background())
+	require.NoError(t, err)
+
+	alice := &models.Member{ID: 1, Name: "Alice", Version: 1}
+	writeEvent(t, conn, MemberCreated, alice)
+	writeEvent(t, conn, MemberUpdated, alice)
+	writeEvent(t, conn, MemberDeleted, alice)
+	// Relayed events stay in the feed
+	_, err = NewRelay(conn, WithoutRowLocks()).Poll(context.Background())
+	require.NoError(t, err)
+
+	// Act
+	latest, latestErr := feed.Latest(context.Background())
+	events, afterErr := feed.After(context.Background(), 1, 10)
+
+	// Assert
+	require.NoError(t, latestErr)
+	require.NoError(t, afterErr)
+	assert.Zero(t, empty)
+	assert.Equal(t, int64(3), latest)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, int64(2), events[0].ID)
+		assert.Equal(t, MemberUpdated, events[0].Type)
+		assert.Equal(t, MemberDeleted, events[1].Type)
+		member, err := events[1].Member()
+		require.NoError(t, err)
+		assert.Equal(t, "Alice", member.Name)
+	}
+}
+
+func TestFeedWaitsForEventsToSettle(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	now := time.Now().UTC()
+	feed := NewFeed(conn, WithSettleDelay(10*time.Second))
+	feed.now = func() time.Time { return now }
+	insertEvent(t, conn, 1, now.Add(-time.Minute))
+	// Event 2 is still being written while event 3 is committed
+	insertEvent(t, conn, 3, now)
+
+	// Act
+	latest, latestErr := feed.Latest(context.Background())
+	settled, settledErr := feed.After(context.Background(), 0, 10)
+	insertEvent(t, conn, 2, now.Add(-time.Second))
+	now = now.Add(10 * time.Second)
+	later, laterErr := feed.After(context.Background(), 1, 10)
+
+	// Assert
+	require.NoError(t, latestErr)
+	require.NoError(t, settledErr)
+	require.NoError(t, laterErr)
+	assert.Equal(t, int64(1), latest)
+	if assert.Len(t, settled, 1, "event 3 waits until the events before it are committed") {
+		assert.Equal(t, int64(1), settled[0].ID)
+	}
+	if assert.Len(t, later, 2) {
+		assert.Equal(t, int64(2), later[0].ID, "the event committed late is not skipped")
+		assert.Equal(t, int64(3), later[1].ID)
+	}
+}
+
+// insertEvent writes the event of the given id as if it was written at the
+// given time.
+func insertEvent(t *testing.T, conn *sql.DB, id int64, at time.Time) {
+	_, err := conn.Exec(`INSERT INTO outbox (id, event_type, member_id, payload, created_at, available_at)
+	VALUES ($1, $2, 1, '{"id":1,"name":"Alice"}', $3, $3)`, id, MemberUpdated, at)
+	require.NoError(t, err)
+}
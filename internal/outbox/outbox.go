@@ -0,0 +1,61 @@
+// Package outbox implements the transactional outbox of member changes. The
+// repository writes an event in the transaction of every change, so that the
+// event exists if and only if the change was committed, and the Relay delivers
+// the events to the handlers of the side effects.
+package outbox
+
+import (
+	"codelit/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Types of the events. The payload of every event is the member after the
+// change, or before it was deleted.
+const (
+	MemberCreated     = "member.created"
+	MemberUpdated     = "member.updated"
+	MemberDeleted     = "member.deleted"
+	MemberRestored    = "member.restored"
+	MemberRevalidated = "member.revalidated"
+	MemberValidated   = "member.validated"
+)
+
+type Event struct {
+	ID        int64
+	Type      string
+	MemberID  int
+	Payload   json.RawMessage
+	CreatedAt time.Time
+	// Attempts counts the deliveries of the event, including the current one
+	Attempts int
+}
+
+// Member decodes the member in the payload of the event.
+func (e Event) Member() (*models.Member, error) {
+	member := &models.Member{}
+	if err := json.Unmarshal(e.Payload, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// Execer runs statements, like *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Write adds an event about the member to the outbox. tx must be the transaction
+// of the change the event is about.
+func Write(ctx context.Context, tx Execer, eventType string, member *models.Member) error {
+	payload, err := json.Marshal(member)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(ctx, `INSERT INTO outbox (event_type, member_id, payload, created_at, available_at)
+	VALUES ($1, $2, $3, $4, $5)`, eventType, member.ID, string(payload), now, now)
+	return err
+}
@@ -0,0 +1,343 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// claimLockID is the Postgres advisory lock key held while claiming events, so
+// that a relay claims after the leases of the other relays are committed and
+// the events of a member are not claimed by two relays at the same time.
+const claimLockID = 4206943
+
+// Handler performs the side effect of an event. Events are delivered at least
+// once, so handlers must cope with the same event more than once.
+type Handler interface {
+	HandleEvent(ctx context.Context, event Event) error
+}
+
+// HandlerFunc is a Handler calling a function.
+type HandlerFunc func(ctx context.Context, event Event) error
+
+func (f HandlerFunc) HandleEvent(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// Relay polls the outbox and delivers the pending events to the handlers
+// registered for their type. An event is done once all its handlers succeeded.
+// Failed events are retried later with exponential backoff, and events claimed
+// by a relay that stopped before finishing them are claimed again once their
+// lease expired. The events of a member are delivered one at a time in the order
+// they were written: an event waits while an earlier one of its member is leased
+// or backing off. Processed events are deleted after the retention period.
+type Relay struct {
+	db       *sql.DB
+	handlers map[string][]Handler
+
+	interval       time.Duration
+	batchSize      int
+	lease          time.Duration
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	lock           bool
+	retention      time.Duration
+	cleanupEvery   time.Duration
+	cleanedAt      time.Time
+	now            func() time.Time
+}
+
+type Option func(*Relay)
+
+// WithInterval sets how long the relay waits before polling again when the
+// outbox had no more pending events.
+func WithInterval(interval time.Duration) Option {
+	return func(r *Relay) {
+		r.interval = interval
+	}
+}
+
+// WithBatchSize sets how many events are claimed and delivered at a time.
+func WithBatchSize(size int) Option {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+// WithLease sets how long claimed events are left alone by other relays. It
+// must be longer than the handlers take.
+func WithLease(lease time.Duration) Option {
+	return func(r *Relay) {
+		r.lease = lease
+	}
+}
+
+// WithMaxAttempts sets how many times an event is delivered before the relay
+// gives up on it. A zero value retries forever.
+func WithMaxAttempts(attempts int) Option {
+	return func(r *Relay) {
+		r.maxAttempts = attempts
+	}
+}
+
+// WithBackoff sets the wait before retrying a failed event, which doubles after
+// every attempt up to max.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(r *Relay) {
+		r.initialBackoff = initial
+		r.maxBackoff = max
+	}
+}
+
+// WithRetention sets how long processed events are kept, for the consumers of
+// the Feed, before they are deleted. A zero retention keeps them forever.
+func WithRetention(retention time.Duration) Option {
+	return func(r *Relay) {
+		r.retention = retention
+	}
+}
+
+// WithoutRowLocks claims events without FOR UPDATE SKIP LOCKED, for databases
+// such as SQLite which do not support it and only have a single writer anyway.
+func WithoutRowLocks() Option {
+	return func(r *Relay) {
+		r.lock = false
+	}
+}
+
+func NewRelay(db *sql.DB, opts ...Option) *Relay {
+	r := &Relay{
+		db:             db,
+		handlers:       map[string][]Handler{},
+		interval:       time.Second,
+		batchSize:      50,
+		lease:          time.Minute,
+		maxAttempts:    10,
+		initialBackoff: time.Second,
+		maxBackoff:     5 * time.Minute,
+		lock:           true,
+		retention:      7 * 24 * time.Hour,
+		cleanupEvery:   time.Hour,
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Handle registers a handler of the events of the given type. Handlers must be
+// registered before Run is called.
+func (r *Relay) Handle(eventType string, handler Handler) {
+	r.handlers[eventType] = append(r.handlers[eventType], handler)
+}
+
+// Run delivers the pending events until ctx is done, then waits for the events
+// being delivered and returns nil.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		delivered, err := r.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Could not relay the outbox events: %v", err)
+		}
+		if r.retention > 0 && r.now().Sub(r.cleanedAt) >= r.cleanupEvery {
+			if _, err := r.Cleanup(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Could not delete the processed outbox events: %v", err)
+			}
+			r.cleanedAt = r.now()
+		}
+		if err == nil && delivered == r.batchSize {
+			// There are probably more events waiting
+			continue
+		}
+
+		timer := time.NewTimer(r.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll claims a batch of pending events and delivers them, the members
+// concurrently and the events of each member in order. It returns the number of
+// events claimed.
+func (r *Relay) Poll(ctx context.Context) (int, error) {
+	events, err := r.claim(ctx)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	// Events are claimed in id order, which each member keeps
+	byMember := map[int][]Event{}
+	for _, event := range events {
+		byMember[event.MemberID] = append(byMember[event.MemberID], event)
+	}
+	var wg sync.WaitGroup
+	for _, events := range byMember {
+		wg.Add(1)
+		go func(events []Event) {
+			defer wg.Done()
+			for i, event := range events {
+				if !r.deliver(ctx, event) {
+					r.release(events[i+1:])
+					return
+				}
+			}
+		}(events)
+	}
+	wg.Wait()
+	return len(events), nil
+}
+
+// Cleanup deletes the events processed longer ago than the retention period and
+// returns how many were deleted.
+func (r *Relay) Cleanup(ctx context.Context) (int, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM outbox WHERE processed_at < $1", r.now().UTC().Add(-r.retention))
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
+
+// claim selects the next pending events and leases them, so that other relays
+// skip them until they are done or the lease expires. Relays claim one at a time.
+func (r *Relay) claim(ctx context.Context) ([]Event, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if r.lock {
+		// SKIP LOCKED alone would let a relay claim the next event of a member
+		// while the claim of the earlier one is not committed yet
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", claimLockID); err != nil {
+			return nil, err
+		}
+	}
+	now := r.now().UTC()
+	query := `SELECT ` + eventColumns + ` FROM outbox
+	WHERE processed_at IS NULL AND available_at <= $1 AND NOT EXISTS (
+		SELECT 1 FROM outbox earlier WHERE earlier.member_id = outbox.member_id
+		AND earlier.processed_at IS NULL AND earlier.available_at > $1 AND earlier.id < outbox.id
+	) ORDER BY id LIMIT $2`
+	if r.lock {
+		query += " FOR UPDATE SKIP LOCKED"
+	}
+	rows, err := tx.QueryContext(ctx, query, now, r.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i].Attempts++
+	}
+	if len(events) == 0 {
+		return events, nil
+	}
+
+	ids, args := eventIDs(events, now.Add(r.lease))
+	_, err = tx.ExecContext(ctx, "UPDATE outbox SET attempts = attempts + 1, available_at = $1 WHERE id IN ("+ids+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	return events, tx.Commit()
+}
+
+// deliver calls the handlers of the event and records the outcome. It returns
+// whether the event is done, so that the next events of the member may follow.
+func (r *Relay) deliver(ctx context.Context, event Event) bool {
+	err := r.handle(ctx, event)
+
+	// The outcome is recorded even when ctx is done, so that delivered events
+	// are not delivered again
+	markCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := r.now().UTC()
+	done := true
+	switch {
+	case err == nil:
+		_, err = r.db.ExecContext(markCtx, "UPDATE outbox SET processed_at = $1, last_error = NULL WHERE id = $2", now, event.ID)
+	case r.maxAttempts > 0 && event.Attempts >= r.maxAttempts:
+		log.Printf("Giving up on outbox event %d (%s of member %d) after %d attempts: %v",
+			event.ID, event.Type, event.MemberID, event.Attempts, err)
+		_, err = r.db.ExecContext(markCtx, "UPDATE outbox SET processed_at = $1, last_error = $2 WHERE id = $3", now, err.Error(), event.ID)
+	default:
+		log.Printf("Could not deliver outbox event %d (%s of member %d): %v", event.ID, event.Type, event.MemberID, err)
+		_, err = r.db.ExecContext(markCtx, "UPDATE outbox SET available_at = $1, last_error = $2 WHERE id = $3",
+			now.Add(r.backoff(event.Attempts)), err.Error(), event.ID)
+		done = false
+	}
+	if err != nil {
+		// The lease expires and the event is delivered again
+		log.Printf("Could not record the delivery of outbox event %d: %v", event.ID, err)
+		return false
+	}
+	return done
+}
+
+// release gives back claimed events that were not delivered because an earlier
+// event of their member was not done. They are claimed again after it.
+func (r *Relay) release(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ids, args := eventIDs(events, r.now().UTC())
+	_, err := r.db.ExecContext(ctx, "UPDATE outbox SET attempts = attempts - 1, available_at = $1 WHERE id IN ("+ids+")", args...)
+	if err != nil {
+		// Their lease expires instead
+		log.Printf("Could not release the outbox events of member %d: %v", events[0].MemberID, err)
+	}
+}
+
+// eventIDs returns the placeholders of the ids of the events, and the arguments
+// of a query taking the given ones first.
+func eventIDs(events []Event, args ...interface{}) (string, []interface{}) {
+	placeholders := []string{}
+	for _, event := range events {
+		args = append(args, event.ID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+// handle calls every handler of the event, stopping at the first failure. The
+// handlers that succeeded are called again when the event is retried.
+func (r *Relay) handle(ctx context.Context, event Event) error {
+	for _, handler := range r.handlers[event.Type] {
+		if err := handler.HandleEvent(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backoff returns the wait before the attempt following the given one.
+func (r *Relay) backoff(attempt int) time.Duration {
+	backoff := r.initialBackoff
+	for i := 1; i < attempt && backoff < r.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.maxBackoff {
+		backoff = r.maxBackoff
+	}
+	return backoff
+}
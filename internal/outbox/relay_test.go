@@ -0,0 +1,411 @@
+package outbox
+
+import (
+	"codelit/db"
+	"codelit/internal/migrations"
+	"codelit/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// newDB returns a migrated SQLite database, closed when the test ends.
+func newDB(t *testing.T) *sql.DB {
+	files, err := db.Migrations("sqlite")
+	require.NoError(t, err)
+	conn, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "members.db"))
+	require.NoError(t, err)
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+
+	runner, err := migrations.NewRunner(conn, files, migrations.WithoutLock())
+	require.NoError(t, err)
+	_, err = runner.Up(context.Background())
+	require.NoError(t, err)
+	return conn
+}
+
+// writeEvent writes an event in its own transaction.
+func writeEvent(t *testing.T, conn *sql.DB, eventType string, member *models.Member) {
+	tx, err := conn.Begin()
+	require.NoError(t, err)
+	require.NoError(t, Write(context.Background(), tx, eventType, member))
+	require.NoError(t, tx.Commit())
+}
+
+// outboxRow is the delivery state of an event.
+type outboxRow struct {
+	attempts  int
+	lastError sql.NullString
+	processed bool
+}
+
+func readRow(t *testing.T, conn *sql.DB, id int64) outboxRow {
+	t.Helper()
+	row := outboxRow{}
+	err := conn.QueryRow("SELECT attempts, last_error, processed_at IS NOT NULL FROM outbox WHERE id = $1", id).
+		Scan(&row.attempts, &row.lastError, &row.processed)
+	require.NoError(t, err)
+	return row
+}
+
+// failing is a handler failing the given number of times before succeeding.
+func failing(times int, events *[]Event) HandlerFunc {
+	return func(ctx context.Context, event Event) error {
+		*events = append(*events, event)
+		if len(*events) <= times {
+			return errors.New("service unavailable")
+		}
+		return nil
+	}
+}
+
+func TestRelayDeliversEvents(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	alice := &models.Member{ID: 1, Name: "Alice", Type: models.MemberTypeEmployee, Role: "Engineer", Version: 1}
+	writeEvent(t, conn, MemberCreated, alice)
+	writeEvent(t, conn, MemberDeleted, alice)
+
+	relay := NewRelay(conn, WithoutRowLocks())
+	var created []Event
+	relay.Handle(MemberCreated, failing(0, &created))
+
+	// Act
+	delivered, err := relay.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	if assert.Len(t, created, 1) {
+		assert.Equal(t, 1, created[0].MemberID)
+		assert.Equal(t, 1, created[0].Attempts)
+		member, err := created[0].Member()
+		require.NoError(t, err)
+		assert.Equal(t, "Alice", member.Name)
+	}
+	assert.True(t, readRow(t, conn, 1).processed)
+	assert.True(t, readRow(t, conn, 2).processed, "events without handlers are done")
+
+	delivered, err = relay.Poll(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+}
+
+func TestRelayDeliversTheEventsOfAMemberInOrder(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	for id := 1; id <= 3; id++ {
+		member := &models.Member{ID: id, Name: "Alice", Version: 1}
+		writeEvent(t, conn, MemberCreated, member)
+		writeEvent(t, conn, MemberUpdated, member)
+		writeEvent(t, conn, MemberDeleted, member)
+	}
+
+	relay := NewRelay(conn, WithoutRowLocks())
+	var mu sync.Mutex
+	delivered := map[int][]string{}
+	record := HandlerFunc(func(ctx context.Context, event Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered[event.MemberID] = append(delivered[event.MemberID], event.Type)
+		return nil
+	})
+	for _, eventType := range []string{MemberCreated, MemberUpdated, MemberDeleted} {
+		relay.Handle(eventType, record)
+	}
+
+	// Act
+	claimed, err := relay.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 9, claimed)
+	for id := 1; id <= 3; id++ {
+		assert.Equal(t, []string{MemberCreated, MemberUpdated, MemberDeleted}, delivered[id])
+	}
+}
+
+func TestRelayHoldsEventsBehindAFailedOne(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	alice := &models.Member{ID: 1, Name: "Alice", Version: 1}
+	writeEvent(t, conn, MemberCreated, alice)
+	writeEvent(t, conn, MemberUpdated, alice)
+
+	relay := NewRelay(conn, WithoutRowLocks(), WithBackoff(time.Minute, time.Hour))
+	now := time.Now()
+	relay.now = func() time.Time { return now }
+	var events []Event
+	relay.Handle(MemberCreated, failing(1, &events))
+	relay.Handle(MemberUpdated, failing(1, &events))
+
+	// Act
+	_, firstErr := relay.Poll(context.Background())
+	held := readRow(t, conn, 2)
+	early, _ := relay.Poll(context.Background())
+	now = now.Add(time.Minute)
+	retried, secondErr := relay.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.Equal(t, outboxRow{}, held, "the update was released without being delivered")
+	assert.Zero(t, early, "the update waits for the creation to be retried")
+	assert.Equal(t, 2, retried)
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, MemberCreated, events[1].Type)
+		assert.Equal(t, MemberUpdated, events[2].Type)
+		assert.Equal(t, 1, events[2].Attempts)
+	}
+}
+
+// TestConcurrentRelays has two relays claiming one event at a time from the
+// same outbox, on SQLite and, when TEST_DATABASE_URL is set, on Postgres.
+func TestConcurrentRelays(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) {
+		testConcurrentRelays(t, newDB(t), WithoutRowLocks())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		url := os.Getenv("TEST_DATABASE_URL")
+		if url == "" {
+			t.Skip("TEST_DATABASE_URL is not set")
+		}
+		conn, err := sql.Open("postgres", url)
+		require.NoError(t, err)
+		defer conn.Close()
+		files, err := db.Migrations("postgres")
+		require.NoError(t, err)
+		runner, err := migrations.NewRunner(conn, files)
+		require.NoError(t, err)
+		_, err = runner.Up(context.Background())
+		require.NoError(t, err)
+		_, err = conn.Exec("TRUNCATE outbox RESTART IDENTITY")
+		require.NoError(t, err)
+
+		testConcurrentRelays(t, conn)
+	})
+}
+
+func testConcurrentRelays(t *testing.T, conn *sql.DB, opts ...Option) {
+	// Arrange
+	const members = 20
+	for id := 1; id <= members; id++ {
+		member := &models.Member{ID: id, Name: "Alice", Version: 1}
+		writeEvent(t, conn, MemberCreated, member)
+		writeEvent(t, conn, MemberUpdated, member)
+	}
+
+	var mu sync.Mutex
+	delivering := map[int]bool{}
+	delivered := map[int][]string{}
+	var overlaps []int
+	record := HandlerFunc(func(ctx context.Context, event Event) error {
+		mu.Lock()
+		if delivering[event.MemberID] {
+			overlaps = append(overlaps, event.MemberID)
+		}
+		delivering[event.MemberID] = true
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		delivering[event.MemberID] = false
+		delivered[event.MemberID] = append(delivered[event.MemberID], event.Type)
+		return nil
+	})
+	relays := []*Relay{
+		NewRelay(conn, append(opts, WithBatchSize(1))...),
+		NewRelay(conn, append(opts, WithBatchSize(1))...),
+	}
+	for _, relay := range relays {
+		relay.Handle(MemberCreated, record)
+		relay.Handle(MemberUpdated, record)
+	}
+
+	// Act
+	deadline := time.Now().Add(10 * time.Second)
+	var wg sync.WaitGroup
+	for _, relay := range relays {
+		wg.Add(1)
+		go func(relay *Relay) {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				if _, err := relay.Poll(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+				var pending int
+				if err := conn.QueryRow("SELECT COUNT(*) FROM outbox WHERE processed_at IS NULL").Scan(&pending); err != nil {
+					t.Error(err)
+					return
+				}
+				if pending == 0 {
+					return
+				}
+			}
+		}(relay)
+	}
+	wg.Wait()
+
+	// Assert
+	assert.Empty(t, overlaps, "the events of a member are delivered one at a time")
+	for id := 1; id <= members; id++ {
+		assert.Equal(t, []string{MemberCreated, MemberUpdated}, delivered[id], "member %d", id)
+	}
+}
+
+func TestRelayCleanup(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	alice := &models.Member{ID: 1, Name: "Alice", Version: 1}
+	writeEvent(t, conn, MemberCreated, alice)
+	writeEvent(t, conn, MemberUpdated, alice)
+
+	relay := NewRelay(conn, WithoutRowLocks(), WithRetention(24*time.Hour))
+	now := time.Now()
+	relay.now = func() time.Time { return now }
+	_, err := relay.Poll(context.Background())
+	require.NoError(t, err)
+	writeEvent(t, conn, MemberDeleted, alice)
+
+	// Act
+	kept, keptErr := relay.Cleanup(context.Background())
+	now = now.Add(24*time.Hour + time.Second)
+	deleted, deletedErr := relay.Cleanup(context.Background())
+
+	// Assert
+	require.NoError(t, keptErr)
+	require.NoError(t, deletedErr)
+	assert.Zero(t, kept)
+	assert.Equal(t, 2, deleted)
+	var ids []int64
+	rows, err := conn.Query("SELECT id FROM outbox")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	assert.Equal(t, []int64{3}, ids, "pending events are kept")
+}
+
+func TestRelayRetriesFailedEvents(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	writeEvent(t, conn, MemberCreated, &models.Member{ID: 1, Name: "Alice", Version: 1})
+
+	relay := NewRelay(conn, WithoutRowLocks(), WithBackoff(time.Minute, time.Hour))
+	now := time.Now()
+	relay.now = func() time.Time { return now }
+	var events []Event
+	relay.Handle(MemberCreated, failing(1, &events))
+
+	// Act
+	_, firstErr := relay.Poll(context.Background())
+	failed := readRow(t, conn, 1)
+	early, _ := relay.Poll(context.Background())
+	now = now.Add(time.Minute)
+	retried, secondErr := relay.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.Equal(t, outboxRow{attempts: 1, lastError: sql.NullString{String: "service unavailable", Valid: true}}, failed)
+	assert.Zero(t, early, "the event is not retried before its backoff")
+	assert.Equal(t, 1, retried)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, 2, events[1].Attempts)
+	}
+	assert.Equal(t, outboxRow{attempts: 2, processed: true}, readRow(t, conn, 1))
+}
+
+func TestRelayGivesUpAfterMaxAttempts(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	writeEvent(t, conn, MemberCreated, &models.Member{ID: 1, Name: "Alice", Version: 1})
+
+	relay := NewRelay(conn, WithoutRowLocks(), WithMaxAttempts(1))
+	var events []Event
+	relay.Handle(MemberCreated, failing(1, &events))
+
+	// Act
+	_, err := relay.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	row := readRow(t, conn, 1)
+	assert.True(t, row.processed)
+	assert.Equal(t, "service unavailable", row.lastError.String)
+}
+
+func TestRelayRedeliversExpiredLeases(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	writeEvent(t, conn, MemberCreated, &models.Member{ID: 1, Name: "Alice", Version: 1})
+
+	relay := NewRelay(conn, WithoutRowLocks(), WithLease(time.Minute))
+	now := time.Now()
+	relay.now = func() time.Time { return now }
+	var events []Event
+	relay.Handle(MemberCreated, failing(0, &events))
+
+	// A relay that stopped after claiming the event
+	claimed, err := relay.claim(context.Background())
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	// Act
+	leased, _ := relay.Poll(context.Background())
+	now = now.Add(time.Minute)
+	expired, err := relay.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Zero(t, leased, "leased events are skipped")
+	assert.Equal(t, 1, expired)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, 2, events[0].Attempts)
+	}
+}
+
+func TestRolledBackChangesHaveNoEvent(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	tx, err := conn.Begin()
+	require.NoError(t, err)
+	require.NoError(t, Write(context.Background(), tx, MemberCreated, &models.Member{ID: 1, Name: "Alice", Version: 1}))
+	require.NoError(t, tx.Rollback())
+
+	relay := NewRelay(conn, WithoutRowLocks())
+
+	// Act
+	delivered, err := relay.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+}
+
+func TestRelayBackoff(t *testing.T) {
+	relay := NewRelay(nil, WithBackoff(time.Second, 10*time.Second))
+
+	assert.Equal(t, time.Second, relay.backoff(1))
+	assert.Equal(t, 2*time.Second, relay.backoff(2))
+	assert.Equal(t, 8*time.Second, relay.backoff(4))
+	assert.Equal(t, 10*time.Second, relay.backoff(5))
+	assert.Equal(t, 10*time.Second, relay.backoff(50))
+}
@@ -0,0 +1,84 @@
+// Package purge removes for good the members deleted longer ago than their
+// retention period. Deleted members are kept until then so that accidental
+// deletions can be restored.
+package purge
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Purger removes the members deleted before a time, see
+// repositories.MemberRepository.
+type Purger interface {
+	PurgeDeletedMembers(ctx context.Context, deletedBefore time.Time) (int, error)
+}
+
+// Job purges the members deleted longer ago than the retention period, at a
+// regular interval.
+type Job struct {
+	purger    Purger
+	retention time.Duration
+	interval  time.Duration
+	now       func() time.Time
+}
+
+type Option func(*Job)
+
+// WithInterval sets how often the deleted members are purged.
+func WithInterval(interval time.Duration) Option {
+	return func(j *Job) {
+		j.interval = interval
+	}
+}
+
+// WithClock sets the clock the retention period is counted from, time.Now by
+// default.
+func WithClock(now func() time.Time) Option {
+	return func(j *Job) {
+		j.now = now
+	}
+}
+
+// NewJob returns a job purging the members deleted longer ago than retention.
+func NewJob(purger Purger, retention time.Duration, opts ...Option) *Job {
+	j := &Job{
+		purger:    purger,
+		retention: retention,
+		interval:  time.Hour,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Run purges the deleted members right away then at every interval, until ctx
+// is done, and returns nil.
+func (j *Job) Run(ctx context.Context) error {
+	for {
+		purged, err := j.Purge(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Could not purge the deleted members: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("Purged %d members deleted more than %s ago", purged, j.retention)
+		}
+
+		timer := time.NewTimer(j.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// Purge removes the members deleted longer ago than the retention period and
+// returns how many were removed.
+func (j *Job) Purge(ctx context.Context) (int, error) {
+	return j.purger.PurgeDeletedMembers(ctx, j.now().Add(-j.retention))
+}
@@ -0,0 +1,76 @@
+package purge
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/repositories"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurge(t *testing.T) {
+	// Arrange
+	now := time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC)
+	repo := repositories.NewMemoryRepository(repositories.WithMemoryClock(func() time.Time { return now }))
+	ctx := context.Background()
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		require.NoError(t, repo.CreateMember(ctx, &models.Member{Name: name, Type: models.MemberTypeContractor, Duration: 6}))
+	}
+	require.NoError(t, repo.DeleteMember(ctx, 1, 0))
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, repo.DeleteMember(ctx, 2, 0))
+	job := NewJob(repo, 30*24*time.Hour, WithClock(func() time.Time { return now }))
+
+	// Act
+	now = now.Add(29*24*time.Hour + time.Minute)
+	purged, err := job.Purge(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged, "only Alice was deleted more than 30 days ago")
+	list, err := repo.ListMembers(ctx, repositories.ListOptions{Limit: 10, IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Total)
+	assert.Equal(t, "Bob", list.Members[0].Name)
+	assert.NotNil(t, list.Members[0].DeletedAt)
+}
+
+func TestRunPurgesUntilDone(t *testing.T) {
+	// Arrange
+	purger := &recordingPurger{calls: make(chan time.Time, 10)}
+	now := time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC)
+	job := NewJob(purger, time.Hour, WithInterval(time.Millisecond), WithClock(func() time.Time { return now }))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	// Act
+	go func() { done <- job.Run(ctx) }()
+	first := <-purger.calls
+	<-purger.calls
+	cancel()
+
+	// Assert
+	assert.Equal(t, now.Add(-time.Hour), first)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was done")
+	}
+}
+
+// recordingPurger sends the cutoff of every purge on calls.
+type recordingPurger struct {
+	calls chan time.Time
+}
+
+func (p *recordingPurger) PurgeDeletedMembers(ctx context.Context, deletedBefore time.Time) (int, error) {
+	select {
+	case p.calls <- deletedBefore:
+	default:
+	}
+	return 0, nil
+}
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"codelit/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// SystemActor is the actor of the changes made without an actor in their context.
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// ContextWithActor returns a context whose member changes are recorded in the
+// audit trail as made by the actor.
+func ContextWithActor(ctx context.Context, actor models.Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by ContextWithActor, SystemActor when
+// there is none.
+func ActorFromContext(ctx context.Context) models.Actor {
+	actor, _ := ctx.Value(actorKey{}).(models.Actor)
+	if actor.Name == "" {
+		actor.Name = SystemActor
+	}
+	return actor
+}
+
+// AuditFilter selects audit entries. Zero fields do not filter.
+type AuditFilter struct {
+	MemberID int
+	Actor    string
+	// From is inclusive and To exclusive
+	From time.Time
+	To   time.Time
+	// Before is the id the entries precede, for the next page
+	Before int64
+	Limit  int
+}
+
+// matches applies the filter to an entry, like the WHERE clause of ListAudit.
+func (f AuditFilter) matches(entry *models.AuditEntry) bool {
+	return (f.MemberID == 0 || entry.MemberID == f.MemberID) &&
+		(f.Actor == "" || entry.Actor == f.Actor) &&
+		(f.From.IsZero() || !entry.At.Before(f.From)) &&
+		(f.To.IsZero() || entry.At.Before(f.To)) &&
+		(f.Before == 0 || entry.ID < f.Before)
+}
+
+// auditColumns are the columns scanned by scanAudit, in order.
+const auditColumns = "id, member_id, action, actor, request_id, version, changes, at"
+
+// ListAudit returns the audit entries matching the filter, the latest first.
+func (r *DBRepository) ListAudit(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	q := &memberQuery{}
+	if filter.MemberID > 0 {
+		q.where = append(q.where, "member_id = "+q.arg(filter.MemberID))
+	}
+	if filter.Actor != "" {
+		q.where = append(q.where, "actor = "+q.arg(filter.Actor))
+	}
+	if !filter.From.IsZero() {
+		q.where = append(q.where, "at >= "+q.arg(filter.From.UTC()))
+	}
+	if !filter.To.IsZero() {
+		q.where = append(q.where, "at < "+q.arg(filter.To.UTC()))
+	}
+	if filter.Before > 0 {
+		q.where = append(q.where, "id < "+q.arg(filter.Before))
+	}
+	query := "SELECT " + auditColumns + " FROM member_audit" + q.whereClause() + " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT " + q.arg(filter.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, dbError(ctx, err)
+	}
+	defer rows.Close()
+
+	entries := []*models.AuditEntry{}
+	for rows.Next() {
+		entry, err := scanAudit(rows)
+		if err != nil {
+			return nil, dbError(ctx, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, err)
+	}
+	return entries, nil
+}
+
+func scanAudit(row scanner) (*models.AuditEntry, error) {
+	entry := &models.AuditEntry{}
+	var requestID sql.NullString
+	var changes []byte
+	err := row.Scan(&entry.ID, &entry.MemberID, &entry.Action, &entry.Actor, &requestID, &entry.Version, &changes, &entry.At)
+	if err != nil {
+		return nil, err
+	}
+	entry.RequestID = requestID.String
+	entry.At = entry.At.UTC()
+	if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// writeAudit records the change of a member from before to after in the audit
+// trail, in the transaction of the change. Before is nil for a creation and
+// after for a deletion.
+func (r *DBRepository) writeAudit(ctx context.Context, tx *sql.Tx, action string, before, after *models.Member) error {
+	entry := newAuditEntry(ctx, action, before, after, r.now())
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+	requestID := sql.NullString{String: entry.RequestID, Valid: entry.RequestID != ""}
+	_, err = tx.ExecContext(ctx, `INSERT INTO member_audit (member_id, action, actor, request_id, version, changes, at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		entry.MemberID, entry.Action, entry.Actor, requestID, entry.Version, string(changes), entry.At)
+	if err != nil {
+		return dbError(ctx, err)
+	}
+	return nil
+}
+
+// newAuditEntry returns the entry of a change made by the actor of the context.
+func newAuditEntry(ctx context.Context, action string, before, after *models.Member, at time.Time) *models.AuditEntry {
+	actor := ActorFromContext(ctx)
+	member := after
+	if member == nil {
+		member = before
+	}
+	return &models.AuditEntry{
+		MemberID:  member.ID,
+		Action:    action,
+		Actor:     actor.Name,
+		RequestID: actor.RequestID,
+		Version:   member.Version,
+		At:        at.UTC(),
+		Changes:   models.Diff(before, after),
+	}
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"codelit/internal/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActorFromContext(t *testing.T) {
+	ctx := ContextWithActor(context.Background(), models.Actor{Name: "alice@example.com", RequestID: "req-1"})
+
+	assert.Equal(t, models.Actor{Name: "alice@example.com", RequestID: "req-1"}, ActorFromContext(ctx))
+	assert.Equal(t, models.Actor{Name: SystemActor}, ActorFromContext(context.Background()))
+}
+
+func TestListAudit(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	from := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT id, member_id, action, actor, request_id, version, changes, at FROM member_audit "+
+		"WHERE actor = \\$1 AND at >= \\$2 AND at < \\$3 AND id < \\$4 ORDER BY id DESC LIMIT \\$5").
+		WithArgs("alice@example.com", from, to, 10, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "member_id", "action", "actor", "request_id", "version", "changes", "at"}).
+			AddRow(9, 3, "updated", "alice@example.com", "req-1", 2, []byte(`{"role": {"after": "Tech Lead", "before": "Engineer"}}`), at).
+			AddRow(7, 3, "created", "alice@example.com", nil, 1, []byte(`{}`), at))
+
+	// Act
+	entries, err := repo.ListAudit(context.Background(), AuditFilter{Actor: "alice@example.com", From: from, To: to, Before: 10, Limit: 2})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, int64(9), entries[0].ID)
+	assert.Equal(t, "req-1", entries[0].RequestID)
+	assert.JSONEq(t, `"Tech Lead"`, string(entries[0].Changes["role"].After))
+	assert.Equal(t, at, entries[1].At)
+	assert.Empty(t, entries[1].RequestID)
+	assert.Empty(t, entries[1].Changes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
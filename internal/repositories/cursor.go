@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"codelit/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor identifies the last member of a page by the values of its sort keys.
+// It is handed to clients as an opaque base64 token.
+type cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (cursor, error) {
+	c := cursor{}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// sameDirection tells whether the fields are all ascending or all descending,
+// so that a row comparison such as (a, b) > ($1, $2) matches their order.
+func sameDirection(fields []SortField) bool {
+	for _, field := range fields {
+		if field.Desc != fields[0].Desc {
+			return false
+		}
+	}
+	return true
+}
+
+// keysetSort returns the sort in the same form as the sort query parameter.
+func keysetSort(sort []SortField) string {
+	terms := []string{}
+	for _, field := range sort {
+		term := field.Column
+		if field.Desc {
+			term = "-" + term
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, ",")
+}
+
+func sortValue(member *models.Member, column string) string {
+	switch column {
+	case "name":
+		return member.Name
+	case "type":
+		return member.Type
+	case "role":
+		return member.Role
+	case "duration":
+		return strconv.Itoa(member.Duration)
+	default:
+		return strconv.Itoa(member.ID)
+	}
+}
+
+// cursorFor returns the cursor of the member, holding its values of the sort
+// fields and of the id breaking their ties.
+func cursorFor(member *models.Member, sort []SortField) string {
+	c := cursor{Sort: keysetSort(sort)}
+	for _, field := range withIDTiebreak(sort) {
+		c.Values = append(c.Values, sortValue(member, field.Column))
+	}
+	return encodeCursor(c)
+}
+
+// keyset is a cursor checked against the sort of the listing it resumes. Its
+// fields are the sort with the id tie breaker, in the order of the listing.
+type keyset struct {
+	sort   []SortField
+	fields []SortField
+	values []string
+}
+
+// resolveCursor decodes the cursor token. The sort is taken from the cursor when
+// none is given, otherwise it must be the one the cursor was created with.
+func resolveCursor(token string, sort []SortField) (*keyset, error) {
+	c, err := decodeCursor(token)
+	if err != nil {
+		return nil, err
+	}
+	if len(sort) == 0 {
+		if sort, err = ParseSort(c.Sort); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	fields := withIDTiebreak(sort)
+	if c.Sort != keysetSort(sort) || len(c.Values) != len(fields) {
+		return nil, ErrInvalidCursor
+	}
+	return &keyset{sort: sort, fields: fields, values: c.Values}, nil
+}
+
+// applyCursor restricts q to the members after the given cursor and returns the
+// sort to use.
+func (q *memberQuery) applyCursor(token string, sort []SortField) ([]SortField, error) {
+	ks, err := resolveCursor(token, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := []string{}
+	placeholders := []string{}
+	for i, field := range ks.fields {
+		columns = append(columns, field.Column)
+		placeholders = append(placeholders, q.arg(ks.values[i]))
+	}
+	if sameDirection(ks.fields) {
+		q.where = append(q.where, "("+strings.Join(columns, ", ")+") "+afterOperator(ks.fields[0])+" ("+strings.Join(placeholders, ", ")+")")
+		return ks.sort, nil
+	}
+
+	// Mixed directions are compared field by field: (a > $1) OR (a = $1 AND
+	// b < $2) OR (a = $1 AND b = $2 AND id > $3)
+	terms := []string{}
+	for i, field := range ks.fields {
+		conditions := []string{}
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, columns[j]+" = "+placeholders[j])
+		}
+		conditions = append(conditions, field.Column+" "+afterOperator(field)+" "+placeholders[i])
+		terms = append(terms, "("+strings.Join(conditions, " AND ")+")")
+	}
+	q.where = append(q.where, "("+strings.Join(terms, " OR ")+")")
+	return ks.sort, nil
+}
+
+// afterOperator returns the operator selecting the values that come after a
+// value of the field.
+func afterOperator(field SortField) string {
+	if field.Desc {
+		return "<"
+	}
+	return ">"
+}
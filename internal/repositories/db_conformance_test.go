@@ -0,0 +1,62 @@
+package repositories_test
+
+import (
+	"codelit/db"
+	"codelit/internal/migrations"
+	"codelit/internal/repositories"
+	"codelit/internal/repositories/repositorytest"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// TestDBRepositoryConformance runs the conformance suite against the Postgres
+// database given by TEST_DATABASE_URL. It is skipped when the variable is unset.
+func TestDBRepositoryConformance(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	conn, err := sql.Open("postgres", url)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	files, err := db.Migrations("postgres")
+	require.NoError(t, err)
+	runner, err := migrations.NewRunner(conn, files)
+	require.NoError(t, err)
+	_, err = runner.Up(context.Background())
+	require.NoError(t, err)
+
+	repositorytest.Run(t, func(t *testing.T) repositories.MemberRepository {
+		_, err := conn.Exec("TRUNCATE members, outbox, member_audit RESTART IDENTITY")
+		require.NoError(t, err)
+		return repositories.NewDBRepository(conn)
+	})
+}
+
+// TestSQLiteRepositoryConformance runs the conformance suite against a new SQLite
+// database for each test.
+func TestSQLiteRepositoryConformance(t *testing.T) {
+	files, err := db.Migrations("sqlite")
+	require.NoError(t, err)
+
+	repositorytest.Run(t, func(t *testing.T) repositories.MemberRepository {
+		conn, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "members.db"))
+		require.NoError(t, err)
+		conn.SetMaxOpenConns(1)
+		t.Cleanup(func() { conn.Close() })
+
+		runner, err := migrations.NewRunner(conn, files, migrations.WithoutLock())
+		require.NoError(t, err)
+		_, err = runner.Up(context.Background())
+		require.NoError(t, err)
+		return repositories.NewSQLiteRepository(conn)
+	})
+}
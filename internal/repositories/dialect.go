@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/lib/pq"
+)
+
+// tagsColumn converts member tags to and from the tags column.
+type tagsColumn interface {
+	sql.Scanner
+	driver.Valuer
+}
+
+// dialect holds what differs between the databases DBRepository runs on. Filters
+// are fmt formats taking the placeholder of their argument. Tag statements take
+// the member id as $1 and the tag as $2, except mergeTags which takes the tags
+// to replace as $1 and the target tag as $2.
+type dialect struct {
+	// tags wraps a tags field so it can be written to or scanned from the tags column.
+	tags func(tags *[]string) tagsColumn
+
+	nameFilter string // case-insensitive LIKE on the name
+	anyTags    string // members having at least one of the tags
+	allTags    string // members having all the tags
+
+	lockRows string // appended to a SELECT to lock its rows until the end of the transaction
+
+	listTags  string // tag and number of members not deleted, ordered by tag
+	mergeTags string // on the members not deleted, without RETURNING clause
+	addTag    string // matches no row when the member already has the tag
+	removeTag string // matches no row when the member does not have the tag
+
+	payloadMemberID string // id of the member in the payload of a webhook delivery
+}
+
+var postgresDialect = dialect{
+	tags: func(tags *[]string) tagsColumn {
+		// The tags column is not nullable
+		if *tags == nil {
+			*tags = []string{}
+		}
+		return (*pq.StringArray)(tags)
+	},
+
+	nameFilter: "name ILIKE %s",
+	anyTags:    "tags && %s",
+	allTags:    "tags @> %s",
+
+	lockRows: " FOR UPDATE",
+
+	listTags: `SELECT tag, count(*) FROM members, unnest(tags) AS tag
+	WHERE ` + notDeleted + ` GROUP BY tag ORDER BY tag`,
+	// Keeps the position of the first replaced tag
+	mergeTags: `UPDATE members SET tags = ARRAY(
+		SELECT m.tag FROM (
+			SELECT CASE WHEN u.tag = ANY($1) THEN $2 ELSE u.tag END AS tag, u.n
+			FROM unnest(tags) WITH ORDINALITY AS u(tag, n)
+		) m GROUP BY m.tag ORDER BY min(m.n)
+	), version = version + 1, ` + resetValidation + `
+	WHERE tags && $1 AND ` + notDeleted,
+	addTag: `UPDATE members SET tags = array_append(tags, $2), version = version + 1, ` + resetValidation + `
+	WHERE id = $1 AND NOT tags @> ARRAY[$2::TEXT] RETURNING ` + memberColumns,
+	removeTag: `UPDATE members SET tags = array_remove(tags, $2), version = version + 1, ` + resetValidation + `
+	WHERE id = $1 AND tags @> ARRAY[$2::TEXT] RETURNING ` + memberColumns,
+
+	payloadMemberID: "(payload->>'id')::INT",
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Errors returned by every MemberRepository implementation. Callers should
+// test for them with errors.Is since they are usually wrapped.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrValidation  = errors.New("validation failed")
+	ErrUnavailable = errors.New("storage unavailable")
+
+	// ErrVersionMismatch is returned by conditional writes when the stored
+	// version of a member differs from the expected one.
+	ErrVersionMismatch = errors.New("version mismatch")
+)
+
+// Error is a repository error of a given kind (one of the sentinel errors
+// above) that keeps the underlying cause.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func notFound(what string) error {
+	return &Error{Kind: ErrNotFound, Err: errors.New(what + " not found")}
+}
+
+// DBError classifies the errors of the other stores of the database, such as
+// the webhooks, into the repository error kinds like dbError. It returns nil for
+// a nil error.
+func DBError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	return dbError(ctx, err)
+}
+
+// dbError classifies Postgres and SQLite driver errors into the repository error
+// kinds. Errors caused by an expired or canceled context are reported as such,
+// since drivers return their own cancellation errors.
+func dbError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		if errors.Is(err, ctxErr) {
+			return err
+		}
+		return &Error{Kind: ctxErr, Err: err}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23505" || pqErr.Code == "23503":
+			return &Error{Kind: ErrConflict, Err: err}
+		case strings.HasPrefix(string(pqErr.Code), "22") || strings.HasPrefix(string(pqErr.Code), "23"):
+			return &Error{Kind: ErrValidation, Err: err}
+		case strings.HasPrefix(string(pqErr.Code), "08") || strings.HasPrefix(string(pqErr.Code), "53") ||
+			strings.HasPrefix(string(pqErr.Code), "57P"):
+			return &Error{Kind: ErrUnavailable, Err: err}
+		}
+		return err
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch code := sqliteErr.Code(); {
+		case code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY ||
+			code == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+			return &Error{Kind: ErrConflict, Err: err}
+		case code&0xff == sqlite3.SQLITE_CONSTRAINT || code&0xff == sqlite3.SQLITE_MISMATCH:
+			return &Error{Kind: ErrValidation, Err: err}
+		case code&0xff == sqlite3.SQLITE_BUSY || code&0xff == sqlite3.SQLITE_LOCKED ||
+			code&0xff == sqlite3.SQLITE_CANTOPEN || code&0xff == sqlite3.SQLITE_IOERR || code&0xff == sqlite3.SQLITE_FULL:
+			return &Error{Kind: ErrUnavailable, Err: err}
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr) {
+		return &Error{Kind: ErrUnavailable, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"codelit/internal/models"
+	"fmt"
+	"strings"
+)
+
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
+// sortColumns lists the member fields that can be used for ordering.
+var sortColumns = map[string]bool{
+	"id":       true,
+	"name":     true,
+	"type":     true,
+	"role":     true,
+	"duration": true,
+}
+
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// ListOptions holds the paging, filtering and ordering applied when listing members.
+type ListOptions struct {
+	Limit    int
+	Offset   int
+	Type     string
+	Role     string
+	Name     string // case-insensitive substring of the member name
+	Status   string // validation status
+	Tags     []string
+	TagMatch string // TagMatchAny or TagMatchAll, defaults to TagMatchAny
+	Sort     []SortField
+	Cursor   string // opaque token from a previous page, replaces Offset
+
+	// IncludeDeleted lists the deleted members too, with their DeletedAt set
+	IncludeDeleted bool
+}
+
+// ParseSort parses a sort expression such as "name,-id" where a leading "-"
+// means descending order.
+func ParseSort(expr string) ([]SortField, error) {
+	fields := []SortField{}
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Column: part}
+		if strings.HasPrefix(part, "-") {
+			field = SortField{Column: part[1:], Desc: true}
+		}
+		if !sortColumns[field.Column] {
+			return nil, fmt.Errorf("invalid sort field %q", field.Column)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// memberQuery accumulates the WHERE clause and positional arguments of a members query.
+type memberQuery struct {
+	where []string
+	args  []interface{}
+}
+
+func (q *memberQuery) arg(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *memberQuery) whereClause() string {
+	if len(q.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.where, " AND ")
+}
+
+func newMemberQuery(d dialect, opts ListOptions) *memberQuery {
+	q := &memberQuery{}
+	if !opts.IncludeDeleted {
+		q.where = append(q.where, notDeleted)
+	}
+	if opts.Type != "" {
+		q.where = append(q.where, "type = "+q.arg(opts.Type))
+	}
+	if opts.Role != "" {
+		q.where = append(q.where, "role = "+q.arg(opts.Role))
+	}
+	if opts.Status != "" {
+		q.where = append(q.where, "validation_status = "+q.arg(opts.Status))
+	}
+	if opts.Name != "" {
+		q.where = append(q.where, fmt.Sprintf(d.nameFilter, q.arg("%"+escapeLike(opts.Name)+"%")))
+	}
+	if len(opts.Tags) > 0 {
+		filter := d.anyTags
+		if opts.TagMatch == TagMatchAll {
+			filter = d.allTags
+		}
+		tags := []string{}
+		for _, tag := range opts.Tags {
+			tags = append(tags, models.FoldTag(tag))
+		}
+		q.where = append(q.where, fmt.Sprintf(filter, q.arg(d.tags(&tags))))
+	}
+	return q
+}
+
+// withIDTiebreak appends the id to the sort unless it is already part of it, so
+// that the order is deterministic. The id takes the direction of the first field,
+// which keeps single direction sorts comparable as a row in keyset pagination.
+func withIDTiebreak(sort []SortField) []SortField {
+	for _, field := range sort {
+		if field.Column == "id" {
+			return sort
+		}
+	}
+	tiebreak := SortField{Column: "id"}
+	if len(sort) > 0 {
+		tiebreak.Desc = sort[0].Desc
+	}
+	return append(append([]SortField{}, sort...), tiebreak)
+}
+
+func orderClause(sort []SortField) string {
+	terms := []string{}
+	for _, field := range withIDTiebreak(sort) {
+		term := field.Column
+		if field.Desc {
+			term += " DESC"
+		}
+		terms = append(terms, term)
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -1,125 +1,567 @@
 package repositories
 
 import (
-	grpcclient "codelit/internal/client"
-	"codelit/internal/client/pb"
 	"codelit/internal/models"
+	"codelit/internal/outbox"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
-
-	"github.com/lib/pq"
+	"strings"
+	"time"
 )
 
 type MemberRepository interface {
-	GetAllMembers() ([]*models.Member, error)
-	GetMemberByID(id int) (*models.Member, error)
-	CreateMember(member *models.Member) error
-	UpdateMember(member *models.Member) error
-	DeleteMember(id int) error
+	GetAllMembers(ctx context.Context) ([]*models.Member, error)
+	ListMembers(ctx context.Context, opts ListOptions) (*models.MemberList, error)
+	GetMemberByID(ctx context.Context, id int) (*models.Member, error)
+	CreateMember(ctx context.Context, member *models.Member) error
+	UpdateMember(ctx context.Context, member *models.Member) error
+	UpdateMemberFields(ctx context.Context, member *models.Member, fields []string) error
+	// DeleteMember marks the member deleted, which hides it from every read until
+	// it is restored or purged.
+	DeleteMember(ctx context.Context, id int, version int) error
+	// RestoreMember brings a deleted member back as it was and increments its
+	// version. A non-zero version makes the restore conditional like
+	// DeleteMember. It fails with ErrConflict when the member is not deleted.
+	RestoreMember(ctx context.Context, id int, version int) (*models.Member, error)
+	// PurgeDeletedMembers removes for good the members deleted before the given
+	// time, along with the copies of them in the outbox and in the webhook
+	// deliveries, and returns how many were removed. Their audit trail is kept.
+	PurgeDeletedMembers(ctx context.Context, deletedBefore time.Time) (int, error)
+
+	// RecordValidation stores the outcome of validating the given version of a
+	// member. The version is left as it is, only changes of the content increment
+	// it. It fails with ErrVersionMismatch when the member changed since.
+	RecordValidation(ctx context.Context, id int, version int, validation models.Validation) error
+	// RevalidateMember sets the member back to pending, without changing its
+	// version, and validates it again.
+	RevalidateMember(ctx context.Context, id int) (*models.Member, error)
+
+	ListTags(ctx context.Context) ([]models.TagCount, error)
+	MergeTags(ctx context.Context, sources []string, target string) (int, error)
+	AddMemberTag(ctx context.Context, id int, tag string) (*models.Member, error)
+	RemoveMemberTag(ctx context.Context, id int, tag string) (*models.Member, error)
+
+	// ListAudit returns the audit entries of the creations, updates, deletions and
+	// restorations of members matching the filter, the latest first. The actor of the changes
+	// is read from their context, see ContextWithActor.
+	ListAudit(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error)
 }
 
+// memberColumns are the columns scanned by scanMember, in order.
+const memberColumns = "id, name, type, role, duration, tags, version, validation_status, validation_reason, validated_at, deleted_at"
+
+// notDeleted is the condition of the members that are not deleted, which are
+// the only ones reads see.
+const notDeleted = "deleted_at IS NULL"
+
+// resetValidation sets the member back to pending, in the updates changing what
+// was validated: the verdict of the previous version no longer applies.
+const resetValidation = "validation_status = '" + models.ValidationPending + "', validation_reason = NULL, validated_at = NULL"
+
 type DBRepository struct {
-	db *sql.DB
+	db           *sql.DB
+	queryTimeout time.Duration
+	dialect      dialect
+	now          func() time.Time
+}
+
+type Option func(*DBRepository)
+
+// WithQueryTimeout bounds every repository call with the given deadline.
+// A zero duration leaves the caller's context untouched.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(r *DBRepository) {
+		r.queryTimeout = timeout
+	}
 }
 
-func NewDBRepository(db *sql.DB) *DBRepository {
-	return &DBRepository{
-		db: db,
+// WithClock sets the clock dating the audit entries and deletions, time.Now by
+// default.
+func WithClock(now func() time.Time) Option {
+	return func(r *DBRepository) {
+		r.now = now
 	}
 }
 
-func (r *DBRepository) GetAllMembers() ([]*models.Member, error) {
-	rows, err := r.db.Query("SELECT id, name, type, role, duration, tags FROM members")
+// NewDBRepository returns a repository on a Postgres database.
+func NewDBRepository(db *sql.DB, opts ...Option) *DBRepository {
+	r := &DBRepository{
+		db:      db,
+		dialect: postgresDialect,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *DBRepository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.queryTimeout)
+}
+
+func (r *DBRepository) GetAllMembers(ctx context.Context) ([]*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+memberColumns+" FROM members WHERE "+notDeleted)
 	if err != nil {
-		return nil, err
+		return nil, dbError(ctx, err)
 	}
 	defer rows.Close()
 
-	members := []*models.Member{}
-	for rows.Next() {
-		member := &models.Member{}
-		var tags pq.StringArray // Use pq.StringArray to store tags as an array of strings
-		err := rows.Scan(&member.ID, &member.Name, &member.Type, &member.Role, &member.Duration, &tags)
-		if err != nil {
+	members, err := r.scanMembers(rows)
+	if err != nil {
+		return nil, dbError(ctx, err)
+	}
+	return members, nil
+}
+
+func (r *DBRepository) ListMembers(ctx context.Context, opts ListOptions) (*models.MemberList, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	q := newMemberQuery(r.dialect, opts)
+
+	list := &models.MemberList{Limit: opts.Limit, Offset: opts.Offset}
+	err := r.db.QueryRowContext(ctx, "SELECT count(*) FROM members"+q.whereClause(), q.args...).Scan(&list.Total)
+	if err != nil {
+		return nil, dbError(ctx, err)
+	}
+
+	if opts.Cursor != "" {
+		if opts.Sort, err = q.applyCursor(opts.Cursor, opts.Sort); err != nil {
 			return nil, err
 		}
-		member.Tags = []string(tags) // Convert pq.StringArray to []string
-		members = append(members, member)
+		opts.Offset = 0
+		list.Offset = 0
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+
+	// One extra row tells whether there is a next page
+	query := "SELECT " + memberColumns + " FROM members" + q.whereClause() + orderClause(opts.Sort)
+	query += " LIMIT " + q.arg(opts.Limit+1) + " OFFSET " + q.arg(opts.Offset)
+	rows, err := r.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, dbError(ctx, err)
 	}
+	defer rows.Close()
 
-	return members, nil
+	list.Members, err = r.scanMembers(rows)
+	if err != nil {
+		return nil, dbError(ctx, err)
+	}
+	paginate(list, opts)
+	return list, nil
 }
 
-func (r *DBRepository) GetMemberByID(id int) (*models.Member, error) {
-	member := &models.Member{}
+func (r *DBRepository) GetMemberByID(ctx context.Context, id int) (*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	var tags pq.StringArray // Use pq.StringArray to store tags as an array of strings
+	row := r.db.QueryRowContext(ctx, "SELECT "+memberColumns+" FROM members WHERE id = $1 AND "+notDeleted, id)
+	member, err := r.scanMember(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("member")
+		}
+		return nil, dbError(ctx, err)
+	}
+
+	return member, nil
+}
+
+func (r *DBRepository) CreateMember(ctx context.Context, member *models.Member) error {
+	var err error
+	if member.Tags, err = NormalizeTags(member.Tags); err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		query := `INSERT INTO members (name, type, role, duration, tags)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, version`
+		err := tx.QueryRowContext(ctx, query, member.Name, member.Type, member.Role, member.Duration, r.dialect.tags(&member.Tags)).Scan(&member.ID, &member.Version)
+		if err != nil {
+			return dbError(ctx, err)
+		}
+		member.ValidationStatus, member.ValidationReason, member.ValidatedAt, member.DeletedAt = models.ValidationPending, "", nil, nil
+		if err := r.writeAudit(ctx, tx, models.AuditCreated, nil, member); err != nil {
+			return err
+		}
+		return writeEvent(ctx, tx, outbox.MemberCreated, member)
+	})
+}
+
+// UpdateMember overwrites the member, increments its version and sets it back to
+// pending validation. When member.Version is set, the update only happens if the
+// stored version still matches it.
+func (r *DBRepository) UpdateMember(ctx context.Context, member *models.Member) error {
+	var err error
+	if member.Tags, err = NormalizeTags(member.Tags); err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE members SET name = $1, type = $2, role = $3, duration = $4, tags = $5, version = version + 1,
+	` + resetValidation + ` WHERE id = $6`
+	args := []interface{}{member.Name, member.Type, member.Role, member.Duration, r.dialect.tags(&member.Tags), member.ID}
+	if member.Version > 0 {
+		query += " AND version = $7"
+		args = append(args, member.Version)
+	}
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		return r.updateMember(ctx, tx, member, query, args)
+	})
+}
 
-	row := r.db.QueryRow("SELECT * FROM members WHERE id = $1", id)
-	err := row.Scan(&member.ID, &member.Name, &member.Type, &member.Role, &member.Duration, &tags)
+// UpdateMemberFields only writes the given columns of the member, with the same
+// versioning as UpdateMember.
+func (r *DBRepository) UpdateMemberFields(ctx context.Context, member *models.Member, fields []string) error {
+	var err error
+	if member.Tags, err = NormalizeTags(member.Tags); err != nil {
+		return err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	q := &memberQuery{}
+	assignments := []string{}
+	for _, field := range fields {
+		value, ok := memberColumnValue(member, field)
+		if !ok {
+			return fmt.Errorf("unknown member field %q", field)
+		}
+		if field == "tags" {
+			value = r.dialect.tags(&member.Tags)
+		}
+		assignments = append(assignments, field+" = "+q.arg(value))
+	}
+	if len(assignments) == 0 {
+		return nil
+	}
+
+	query := "UPDATE members SET " + strings.Join(assignments, ", ") + ", version = version + 1, " + resetValidation + " WHERE id = " + q.arg(member.ID)
+	if member.Version > 0 {
+		query += " AND version = " + q.arg(member.Version)
+	}
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		return r.updateMember(ctx, tx, member, query, q.args)
+	})
+}
+
+// updateMember runs an update of the member, which must not have its RETURNING
+// clause yet, sets the columns managed by the server on the member, and writes
+// the audit entry and the event of the update.
+func (r *DBRepository) updateMember(ctx context.Context, tx *sql.Tx, member *models.Member, query string, args []interface{}) error {
+	before, err := r.lockMember(ctx, tx, member.ID)
+	if err != nil {
+		return err
+	}
+	after, err := r.scanMember(tx.QueryRowContext(ctx, query+" RETURNING "+memberColumns, args...))
+	if err == sql.ErrNoRows {
+		return staleOrMissing(ctx, tx, member.ID)
+	}
 	if err != nil {
+		return dbError(ctx, err)
+	}
+	member.Version = after.Version
+	member.ValidationStatus, member.ValidationReason, member.ValidatedAt = after.ValidationStatus, after.ValidationReason, after.ValidatedAt
+	member.DeletedAt = after.DeletedAt
+	if err := r.writeAudit(ctx, tx, models.AuditUpdated, before, after); err != nil {
+		return err
+	}
+	return writeEvent(ctx, tx, outbox.MemberUpdated, member)
+}
+
+// lockMember reads the member in the transaction, before changing it, and keeps
+// others from changing it until the transaction ends.
+func (r *DBRepository) lockMember(ctx context.Context, tx *sql.Tx, id int) (*models.Member, error) {
+	member, err := r.scanMember(tx.QueryRowContext(ctx, "SELECT "+memberColumns+" FROM members WHERE id = $1 AND "+notDeleted+r.dialect.lockRows, id))
+	if err == sql.ErrNoRows {
+		return nil, notFound("member")
+	}
+	if err != nil {
+		return nil, dbError(ctx, err)
+	}
+	return member, nil
+}
+
+// DeleteMember marks the member deleted, keeping its version. A non-zero version
+// makes the delete conditional like UpdateMember.
+func (r *DBRepository) DeleteMember(ctx context.Context, id int, version int) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := "UPDATE members SET deleted_at = $1 WHERE id = $2 AND " + notDeleted
+	args := []interface{}{r.now().UTC(), id}
+	if version > 0 {
+		query += " AND version = $3"
+		args = append(args, version)
+	}
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		member, err := r.scanMember(tx.QueryRowContext(ctx, query+" RETURNING "+memberColumns, args...))
+		if err == sql.ErrNoRows && version > 0 {
+			return staleOrMissing(ctx, tx, id)
+		}
+		if err == sql.ErrNoRows {
+			return notFound("member")
+		}
+		if err != nil {
+			return dbError(ctx, err)
+		}
+		member.DeletedAt = nil
+		if err := r.writeAudit(ctx, tx, models.AuditDeleted, member, nil); err != nil {
+			return err
+		}
+		return writeEvent(ctx, tx, outbox.MemberDeleted, member)
+	})
+}
+
+func (r *DBRepository) RestoreMember(ctx context.Context, id int, version int) (*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := "UPDATE members SET deleted_at = NULL, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL"
+	args := []interface{}{id}
+	if version > 0 {
+		query += " AND version = $2"
+		args = append(args, version)
+	}
+	var member *models.Member
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		member, err = r.scanMember(tx.QueryRowContext(ctx, query+" RETURNING "+memberColumns, args...))
 		if err == sql.ErrNoRows {
-			return nil, errors.New("member not found")
+			return notDeletedOrMissing(ctx, tx, id)
+		}
+		if err != nil {
+			return dbError(ctx, err)
 		}
+		if err := r.writeAudit(ctx, tx, models.AuditRestored, nil, member); err != nil {
+			return err
+		}
+		return writeEvent(ctx, tx, outbox.MemberRestored, member)
+	})
+	if err != nil {
 		return nil, err
 	}
+	return member, nil
+}
 
-	member.Tags = []string(tags) // Convert pq.StringArray to []string
+func (r *DBRepository) PurgeDeletedMembers(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	return member, nil
+	// The outbox events and webhook deliveries hold copies of the members, which
+	// are removed with them. The deliveries are found by the member id of their
+	// payload since the events may be gone already.
+	purged := "SELECT id FROM members WHERE deleted_at < $1"
+	deliveries := "SELECT id FROM webhook_deliveries WHERE " + r.dialect.payloadMemberID + " IN (" + purged + ")"
+	count := 0
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		for _, query := range []string{
+			"DELETE FROM webhook_attempts WHERE delivery_id IN (" + deliveries + ")",
+			"DELETE FROM webhook_deliveries WHERE id IN (" + deliveries + ")",
+			"DELETE FROM outbox WHERE member_id IN (" + purged + ")",
+		} {
+			if _, err := tx.ExecContext(ctx, query, deletedBefore.UTC()); err != nil {
+				return dbError(ctx, err)
+			}
+		}
+		result, err := tx.ExecContext(ctx, "DELETE FROM members WHERE deleted_at < $1", deletedBefore.UTC())
+		if err != nil {
+			return dbError(ctx, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return dbError(ctx, err)
+		}
+		count = int(n)
+		return nil
+	})
+	return count, err
 }
 
-func (r *DBRepository) CreateMember(member *models.Member) error {
-	query := `INSERT INTO members (name, type, role, duration, tags)
-	VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	tagsArray := pq.Array(member.Tags) // Convert slice of strings to pq.Array
-	err := r.db.QueryRow(query, member.Name, member.Type, member.Role, member.Duration, tagsArray).Scan(&member.ID)
+func (r *DBRepository) RecordValidation(ctx context.Context, id int, version int, validation models.Validation) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	go validateMember(member) // Validates member concurrently
+	query := `UPDATE members SET validation_status = $1, validation_reason = $2, validated_at = $3
+	WHERE id = $4 AND version = $5 AND ` + notDeleted + ` RETURNING ` + memberColumns
+	reason := sql.NullString{String: validation.Reason, Valid: validation.Reason != ""}
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		member, err := r.scanMember(tx.QueryRowContext(ctx, query, validation.Status, reason, validation.At, id, version))
+		if err == sql.ErrNoRows {
+			return staleOrMissing(ctx, tx, id)
+		}
+		if err != nil {
+			return dbError(ctx, err)
+		}
+		return writeEvent(ctx, tx, outbox.MemberValidated, member)
+	})
+}
+
+func (r *DBRepository) RevalidateMember(ctx context.Context, id int) (*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE members SET validation_status = $1, validation_reason = NULL, validated_at = NULL
+	WHERE id = $2 AND ` + notDeleted + ` RETURNING ` + memberColumns
+	var member *models.Member
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		member, err = r.scanMember(tx.QueryRowContext(ctx, query, models.ValidationPending, id))
+		if err == sql.ErrNoRows {
+			return notFound("member")
+		}
+		if err != nil {
+			return dbError(ctx, err)
+		}
+		return writeEvent(ctx, tx, outbox.MemberRevalidated, member)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
 
+// inTx runs fn in a transaction, which is committed when fn succeeds. The errors
+// of fn are returned as they are.
+func (r *DBRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
+		return dbError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
 		return err
 	}
+	if err := tx.Commit(); err != nil {
+		return dbError(ctx, err)
+	}
+	return nil
+}
 
+// writeEvent adds the event of a member change to the outbox, in the transaction
+// of the change.
+func writeEvent(ctx context.Context, tx *sql.Tx, eventType string, member *models.Member) error {
+	if err := outbox.Write(ctx, tx, eventType, member); err != nil {
+		return dbError(ctx, err)
+	}
 	return nil
 }
 
-func (r *DBRepository) UpdateMember(member *models.Member) error {
-	query := `UPDATE members SET name = $1, type = $2, role = $3, duration = $4, tags = $5
-	WHERE id = $6`
-	tagsArray := pq.Array(member.Tags) // Convert slice of strings to pq.Array
-	_, err := r.db.Exec(query, member.Name, member.Type, member.Role, member.Duration, tagsArray, member.ID)
+// staleOrMissing tells why a conditional write on a member matched no row.
+// Deleted members are missing.
+func staleOrMissing(ctx context.Context, tx *sql.Tx, id int) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM members WHERE id = $1 AND "+notDeleted+")", id).Scan(&exists)
 	if err != nil {
-		return err
+		return dbError(ctx, err)
 	}
-	return nil
+	if exists {
+		return &Error{Kind: ErrVersionMismatch, Err: errors.New("member was modified by someone else")}
+	}
+	return notFound("member")
 }
 
-func (r *DBRepository) DeleteMember(id int) error {
-	query := "DELETE FROM members WHERE id = $1"
-	_, err := r.db.Exec(query, id)
+// notDeletedOrMissing tells why the restore of a member matched no row: it is
+// missing, not deleted, or deleted with another version.
+func notDeletedOrMissing(ctx context.Context, tx *sql.Tx, id int) error {
+	var deleted bool
+	err := tx.QueryRowContext(ctx, "SELECT deleted_at IS NOT NULL FROM members WHERE id = $1", id).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		return notFound("member")
+	}
 	if err != nil {
-		return err
+		return dbError(ctx, err)
 	}
-	return nil
+	if !deleted {
+		return &Error{Kind: ErrConflict, Err: errors.New("member is not deleted")}
+	}
+	return &Error{Kind: ErrVersionMismatch, Err: errors.New("member was modified by someone else")}
 }
 
-func validateMember(member *models.Member) {
-	conn := grpcclient.StartGRPC()
-	client := pb.NewGreeterClient(conn)
+func memberColumnValue(member *models.Member, column string) (interface{}, bool) {
+	switch column {
+	case "name":
+		return member.Name, true
+	case "type":
+		return member.Type, true
+	case "role":
+		return member.Role, true
+	case "duration":
+		return member.Duration, true
+	case "tags":
+		return member.Tags, true
+	}
+	return nil, false
+}
 
-	req := &pb.HelloRequest{
-		Name: member.Name,
+// paginate trims the extra row fetched by ListMembers and sets the cursor of the
+// next page.
+func paginate(list *models.MemberList, opts ListOptions) {
+	if len(list.Members) <= opts.Limit {
+		return
 	}
-	res, err := client.SayHello(context.Background(), req)
+	list.Members = list.Members[:opts.Limit]
+	list.NextCursor = cursorFor(list.Members[opts.Limit-1], opts.Sort)
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMember reads a row selected with memberColumns.
+func (r *DBRepository) scanMember(row scanner) (*models.Member, error) {
+	member := &models.Member{}
+	var reason sql.NullString
+	var validatedAt, deletedAt sql.NullTime
+	err := row.Scan(&member.ID, &member.Name, &member.Type, &member.Role, &member.Duration, r.dialect.tags(&member.Tags),
+		&member.Version, &member.ValidationStatus, &reason, &validatedAt, &deletedAt)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	setValidation(member, reason, validatedAt)
+	if deletedAt.Valid {
+		at := deletedAt.Time.UTC()
+		member.DeletedAt = &at
 	}
-	fmt.Println(res)
+	return member, nil
+}
+
+func setValidation(member *models.Member, reason sql.NullString, validatedAt sql.NullTime) {
+	member.ValidationReason = reason.String
+	member.ValidatedAt = nil
+	if validatedAt.Valid {
+		at := validatedAt.Time
+		member.ValidatedAt = &at
+	}
+}
+
+func (r *DBRepository) scanMembers(rows *sql.Rows) ([]*models.Member, error) {
+	members := []*models.Member{}
+	for rows.Next() {
+		member, err := r.scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
 }
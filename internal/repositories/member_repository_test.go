@@ -2,7 +2,11 @@ package repositories
 
 import (
 	"codelit/internal/models"
+	"context"
+	"net"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/lib/pq"
@@ -15,15 +19,15 @@ func TestGetAllMembers(t *testing.T) {
 	defer db.Close()
 	repo := NewDBRepository(db)
 
-	columns := []string{"id", "name", "type", "role", "duration", "tags"}
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
 	rows := sqlmock.NewRows(columns).
-		AddRow(1, "John Doe", "employee", "Software Engineer", 5, pq.Array([]string{"tag1", "tag2"})).
-		AddRow(2, "Jane Smith", "employee", "Project Manager", 7, pq.Array([]string{"tag3", "tag4"}))
+		AddRow(1, "John Doe", "employee", "Software Engineer", 5, pq.Array([]string{"tag1", "tag2"}), 1, "pending", nil, nil, nil).
+		AddRow(2, "Jane Smith", "employee", "Project Manager", 7, pq.Array([]string{"tag3", "tag4"}), 1, "pending", nil, nil, nil)
 
 	// Act
-	mock.ExpectQuery("SELECT id, name, type, role, duration, tags FROM members").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT id, name, type, role, duration, tags, version, validation_status, validation_reason, validated_at, deleted_at FROM members").WillReturnRows(rows)
 
-	members, err := repo.GetAllMembers()
+	members, err := repo.GetAllMembers(context.Background())
 
 	// Assert
 	assert.NoError(t, err)
@@ -40,16 +44,16 @@ func TestGetMemberByID(t *testing.T) {
 	defer db.Close()
 	repo := NewDBRepository(db)
 
-	columns := []string{"id", "name", "type", "role", "duration", "tags"}
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
 	row := sqlmock.NewRows(columns).
-		AddRow(1, "John Doe", "employee", "Software Engineer", 5, pq.Array([]string{"tag1", "tag2"}))
+		AddRow(1, "John Doe", "employee", "Software Engineer", 5, pq.Array([]string{"tag1", "tag2"}), 1, "pending", nil, nil, nil)
 
-	mock.ExpectQuery("SELECT \\* FROM members WHERE id = \\$1").
+	mock.ExpectQuery("SELECT id, name, type, role, duration, tags, version, validation_status, validation_reason, validated_at, deleted_at FROM members WHERE id = \\$1").
 		WithArgs(1).
 		WillReturnRows(row)
 
 	// Act
-	member, err := repo.GetMemberByID(1)
+	member, err := repo.GetMemberByID(context.Background(), 1)
 
 	// Assert
 	assert.NoError(t, err)
@@ -59,16 +63,44 @@ func TestGetMemberByID(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestQueryTimeout(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db, WithQueryTimeout(10*time.Millisecond))
+
+	mock.ExpectQuery("SELECT id, name, type, role, duration, tags, version, validation_status, validation_reason, validated_at, deleted_at FROM members WHERE id = \\$1").
+		WithArgs(1).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// Act
+	_, err := repo.GetMemberByID(context.Background(), 1)
+
+	// Assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
 func TestCreateMember(t *testing.T) {
 	// Arrange
 	db, mock, _ := sqlmock.New()
 	defer db.Close()
 	repo := NewDBRepository(db)
 
-	query := "INSERT INTO members \\(name, type, role, duration, tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5\\) RETURNING id"
+	query := "INSERT INTO members \\(name, type, role, duration, tags\\) VALUES \\(\\$1, \\$2, \\$3, \\$4, \\$5\\) RETURNING id, version"
+	mock.ExpectBegin()
 	mock.ExpectQuery(query).
 		WithArgs("John Doe", "employee", "Software Engineer", 5, pq.Array([]string{"tag1", "tag2"})).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).AddRow(1, 1))
+	mock.ExpectExec("INSERT INTO member_audit \\(member_id, action, actor, request_id, version, changes, at\\)").
+		WithArgs(1, "created", "system", nil, 1,
+			`{"duration":{"before":null,"after":5},"name":{"before":null,"after":"John Doe"},"role":{"before":null,"after":"Software Engineer"},"tags":{"before":null,"after":["tag1","tag2"]},"type":{"before":null,"after":"employee"}}`,
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox \\(event_type, member_id, payload, created_at, available_at\\)").
+		WithArgs("member.created", 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	member := &models.Member{
 		Name:     "John Doe",
@@ -79,7 +111,7 @@ func TestCreateMember(t *testing.T) {
 	}
 
 	// Act
-	err := repo.CreateMember(member)
+	err := repo.CreateMember(context.Background(), member)
 
 	// Assert
 	assert.NoError(t, err)
@@ -87,6 +119,48 @@ func TestCreateMember(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestFailedCreateWritesNoEvent(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO members").
+		WillReturnError(&pq.Error{Code: "23514"})
+	mock.ExpectRollback()
+
+	// Act
+	err := repo.CreateMember(context.Background(), &models.Member{Name: "Mary Major", Type: "contractor", Duration: 6})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateMemberRollsBackWithoutEvent(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO members").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version"}).AddRow(4, 1))
+	mock.ExpectExec("INSERT INTO member_audit").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WillReturnError(&pq.Error{Code: "08006"})
+	mock.ExpectRollback()
+
+	// Act
+	err := repo.CreateMember(context.Background(), &models.Member{Name: "Mary Major", Type: "contractor", Duration: 6})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUpdateMember(t *testing.T) {
 	// Arrange
 	db, mock, _ := sqlmock.New()
@@ -94,10 +168,24 @@ func TestUpdateMember(t *testing.T) {
 
 	repo := NewDBRepository(db)
 
-	query := "UPDATE members SET name = \\$1, type = \\$2, role = \\$3, duration = \\$4, tags = \\$5 WHERE id = \\$6"
-	mock.ExpectExec(query).
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	query := "UPDATE members SET name = \\$1, type = \\$2, role = \\$3, duration = \\$4, tags = \\$5, version = version \\+ 1,\\s+validation_status = 'pending', validation_reason = NULL, validated_at = NULL WHERE id = \\$6 RETURNING id, .*, validated_at"
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, .*, validated_at, deleted_at FROM members WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John Doe", "employee", "Engineer", 0, pq.Array([]string{"tag1"}), 1, "valid", nil, nil, nil))
+	mock.ExpectQuery(query).
 		WithArgs("John Doe", "employee", "Software Engineer", 5, pq.Array([]string{"tag1", "tag2"}), 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John Doe", "employee", "Software Engineer", 5, pq.Array([]string{"tag1", "tag2"}), 2, "pending", nil, nil, nil))
+	mock.ExpectExec("INSERT INTO member_audit").
+		WithArgs(1, "updated", "alice", "req-1", 2,
+			`{"duration":{"before":null,"after":5},"role":{"before":"Engineer","after":"Software Engineer"},"tags":{"before":["tag1"],"after":["tag1","tag2"]}}`,
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs("member.updated", 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	member := &models.Member{
 		ID:       1,
@@ -109,10 +197,12 @@ func TestUpdateMember(t *testing.T) {
 	}
 
 	// Act
-	err := repo.UpdateMember(member)
+	ctx := ContextWithActor(context.Background(), models.Actor{Name: "alice", RequestID: "req-1"})
+	err := repo.UpdateMember(ctx, member)
 
 	// Assert the results
 	assert.NoError(t, err)
+	assert.Equal(t, 2, member.Version)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -122,15 +212,552 @@ func TestDeleteMember(t *testing.T) {
 	defer db.Close()
 	repo := NewDBRepository(db)
 
-	query := "DELETE FROM members WHERE id = \\$1"
-	mock.ExpectExec(query).
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	query := "UPDATE members SET deleted_at = \\$1 WHERE id = \\$2 AND deleted_at IS NULL RETURNING id, .*, validated_at"
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John Doe", "contractor", "", 6, pq.Array([]string{}), 2, "pending", nil, nil, nil))
+	mock.ExpectExec("INSERT INTO member_audit").
+		WithArgs(1, "deleted", "system", nil, 2,
+			`{"duration":{"before":6,"after":null},"name":{"before":"John Doe","after":null},"type":{"before":"contractor","after":null}}`,
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs("member.deleted", 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// Act
+	err := repo.DeleteMember(context.Background(), 1, 0)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListMembers(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	where := " WHERE deleted_at IS NULL AND type = \\$1 AND name ILIKE \\$2 AND tags @> \\$3"
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM members"+where).
+		WithArgs("employee", "%jo\\%%", pq.Array([]string{"go", "sql"})).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	rows := sqlmock.NewRows(columns).
+		AddRow(3, "John Doe", "employee", "Software Engineer", 0, pq.Array([]string{"go", "sql"}), 1, "pending", nil, nil, nil)
+	mock.ExpectQuery("SELECT id, name, type, role, duration, tags, version, validation_status, validation_reason, validated_at, deleted_at FROM members"+where+
+		" ORDER BY name, id DESC LIMIT \\$4 OFFSET \\$5").
+		WithArgs("employee", "%jo\\%%", pq.Array([]string{"go", "sql"}), 11, 20).
+		WillReturnRows(rows)
+
+	sort, err := ParseSort("name,-id")
+	assert.NoError(t, err)
+
+	// Act
+	list, err := repo.ListMembers(context.Background(), ListOptions{
+		Limit:    10,
+		Offset:   20,
+		Type:     "employee",
+		Name:     "jo%",
+		Tags:     []string{"go", "sql"},
+		TagMatch: TagMatchAll,
+		Sort:     sort,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 12, list.Total)
+	assert.Len(t, list.Members, 1)
+	assert.Equal(t, "John Doe", list.Members[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestParseSortRejectsUnknownField(t *testing.T) {
+	_, err := ParseSort("name,-salary")
+
+	assert.EqualError(t, err, `invalid sort field "salary"`)
+}
+
+func TestListMembersWithCursor(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	sort, _ := ParseSort("name")
+	token := encodeCursor(cursor{Sort: "name", Values: []string{"Jane Smith", "2"}})
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM members").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	rows := sqlmock.NewRows(columns).
+		AddRow(7, "John Doe", "employee", "Software Engineer", 0, pq.Array([]string{}), 1, "pending", nil, nil, nil).
+		AddRow(4, "Mary Major", "contractor", "", 6, pq.Array([]string{}), 1, "pending", nil, nil, nil)
+	mock.ExpectQuery("SELECT id, name, type, role, duration, tags, version, validation_status, validation_reason, validated_at, deleted_at FROM members WHERE deleted_at IS NULL AND \\(name, id\\) > \\(\\$1, \\$2\\)"+
+		" ORDER BY name, id LIMIT \\$3 OFFSET \\$4").
+		WithArgs("Jane Smith", "2", 2, 0).
+		WillReturnRows(rows)
+
+	// Act
+	list, err := repo.ListMembers(context.Background(), ListOptions{Limit: 1, Sort: sort, Cursor: token})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 5, list.Total)
+	assert.Len(t, list.Members, 1)
+	assert.Equal(t, 7, list.Members[0].ID)
+	next, err := decodeCursor(list.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, cursor{Sort: "name", Values: []string{"John Doe", "7"}}, next)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListMembersWithMixedSortCursor(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	sort, _ := ParseSort("-duration,name")
+	token := encodeCursor(cursor{Sort: "-duration,name", Values: []string{"6", "Jane Smith", "2"}})
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM members").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	rows := sqlmock.NewRows(columns).
+		AddRow(7, "John Doe", "contractor", "", 6, pq.Array([]string{}), 1, "pending", nil, nil, nil).
+		AddRow(4, "Mary Major", "contractor", "", 3, pq.Array([]string{}), 1, "pending", nil, nil, nil)
+	mock.ExpectQuery("SELECT .* FROM members WHERE deleted_at IS NULL AND \\(\\(duration < \\$1\\) OR \\(duration = \\$1 AND name > \\$2\\)"+
+		" OR \\(duration = \\$1 AND name = \\$2 AND id < \\$3\\)\\) ORDER BY duration DESC, name, id DESC LIMIT \\$4 OFFSET \\$5").
+		WithArgs("6", "Jane Smith", "2", 2, 0).
+		WillReturnRows(rows)
+
+	// Act
+	list, err := repo.ListMembers(context.Background(), ListOptions{Limit: 1, Sort: sort, Cursor: token})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, list.Members, 1)
+	next, err := decodeCursor(list.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, cursor{Sort: "-duration,name", Values: []string{"6", "John Doe", "7"}}, next)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListMembersRejectsForeignCursor(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	sort, _ := ParseSort("-duration")
+	token := encodeCursor(cursor{Sort: "name", Values: []string{"Jane Smith", "2"}})
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM members").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+
+	// Act
+	_, err := repo.ListMembers(context.Background(), ListOptions{Limit: 1, Sort: sort, Cursor: token})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMemberByIDNotFound(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	mock.ExpectQuery("SELECT id, name, type, role, duration, tags, version, validation_status, validation_reason, validated_at, deleted_at FROM members WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}))
+
+	// Act
+	_, err := repo.GetMemberByID(context.Background(), 1)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMemberNotFound(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE members SET deleted_at = \\$1 WHERE id = \\$2 AND deleted_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	// Act
+	err := repo.DeleteMember(context.Background(), 1, 0)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDriverErrorsAreClassified(t *testing.T) {
+	tests := []struct {
+		err  error
+		kind error
+	}{
+		{&pq.Error{Code: "23505"}, ErrConflict},
+		{&pq.Error{Code: "22001"}, ErrValidation},
+		{&pq.Error{Code: "08006"}, ErrUnavailable},
+		{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, ErrUnavailable},
+	}
+
+	for _, test := range tests {
+		// Arrange
+		db, mock, _ := sqlmock.New()
+		repo := NewDBRepository(db)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM members WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").WillReturnError(test.err)
+		mock.ExpectRollback()
+
+		// Act
+		err := repo.UpdateMember(context.Background(), &models.Member{ID: 1})
+
+		// Assert
+		assert.ErrorIs(t, err, test.kind)
+		assert.ErrorIs(t, err, test.err)
+		db.Close()
+	}
+}
+
+func TestUpdateMemberFields(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, .*, validated_at, deleted_at FROM members WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John Doe", "employee", "Engineer", 0, pq.Array([]string{}), 3, "valid", nil, nil, nil))
+	mock.ExpectQuery("UPDATE members SET role = \\$1, tags = \\$2, version = version \\+ 1, validation_status = 'pending', validation_reason = NULL, validated_at = NULL WHERE id = \\$3 AND version = \\$4 RETURNING id, .*, validated_at").
+		WithArgs("Tech Lead", pq.Array([]string{"go"}), 1, 3).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John Doe", "employee", "Tech Lead", 0, pq.Array([]string{"go"}), 4, "pending", nil, nil, nil))
+	mock.ExpectExec("INSERT INTO member_audit").
+		WithArgs(1, "updated", "system", nil, 4,
+			`{"role":{"before":"Engineer","after":"Tech Lead"},"tags":{"before":null,"after":["go"]}}`,
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs("member.updated", 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	member := &models.Member{
+		ID:      1,
+		Name:    "John Doe",
+		Type:    "employee",
+		Role:    "Tech Lead",
+		Tags:    []string{"go"},
+		Version: 3,
+	}
+
+	// Act
+	err := repo.UpdateMemberFields(context.Background(), member, []string{"role", "tags"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 4, member.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateMemberVersionMismatch(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, .*, validated_at, deleted_at FROM members WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John Doe", "contractor", "", 6, pq.Array([]string{}), 3, "valid", nil, nil, nil))
+	mock.ExpectQuery("UPDATE members SET .* WHERE id = \\$6 AND version = \\$7 RETURNING id, .*, validated_at").
+		WithArgs("John Doe", "contractor", "", 6, pq.Array([]string{}), 1, 2).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM members WHERE id = \\$1 AND deleted_at IS NULL\\)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	member := &models.Member{ID: 1, Name: "John Doe", Type: "contractor", Duration: 6, Version: 2}
+
+	// Act
+	err := repo.UpdateMember(context.Background(), member)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMemberWithVersion(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE members SET deleted_at = \\$1 WHERE id = \\$2 AND deleted_at IS NULL AND version = \\$3").
+		WithArgs(sqlmock.AnyArg(), 1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM members WHERE id = \\$1 AND deleted_at IS NULL\\)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	// Act
+	err := repo.DeleteMember(context.Background(), 1, 3)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreMember(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE members SET deleted_at = NULL, version = version \\+ 1 WHERE id = \\$1 AND deleted_at IS NOT NULL AND version = \\$2 RETURNING id, .*, validated_at, deleted_at").
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John Doe", "contractor", "", 6, pq.Array([]string{}), 2, "valid", nil, nil, nil))
+	mock.ExpectExec("INSERT INTO member_audit").
+		WithArgs(1, "restored", "system", nil, 2,
+			`{"duration":{"before":null,"after":6},"name":{"before":null,"after":"John Doe"},"type":{"before":null,"after":"contractor"}}`,
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs("member.restored", 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// Act
+	member, err := repo.RestoreMember(context.Background(), 1, 1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "John Doe", member.Name)
+	assert.Nil(t, member.DeletedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreMemberNotDeleted(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE members SET deleted_at = NULL, version = version \\+ 1 WHERE id = \\$1 AND deleted_at IS NOT NULL RETURNING").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT deleted_at IS NOT NULL FROM members WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted"}).AddRow(false))
+	mock.ExpectRollback()
+
+	// Act
+	_, err := repo.RestoreMember(context.Background(), 1, 0)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreMemberOfOtherVersion(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE members SET deleted_at = NULL, version = version \\+ 1 WHERE id = \\$1 AND deleted_at IS NOT NULL AND version = \\$2").
+		WithArgs(1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT deleted_at IS NOT NULL FROM members WHERE id = \\$1").
 		WithArgs(1).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+		WillReturnRows(sqlmock.NewRows([]string{"deleted"}).AddRow(true))
+	mock.ExpectRollback()
+
+	// Act
+	_, err := repo.RestoreMember(context.Background(), 1, 3)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeDeletedMembers(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	cutoff := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	members := "SELECT id FROM members WHERE deleted_at < \\$1"
+	deliveries := "SELECT id FROM webhook_deliveries WHERE \\(payload->>'id'\\)::INT IN \\(" + members + "\\)"
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM webhook_attempts WHERE delivery_id IN \\(" + deliveries + "\\)").
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("DELETE FROM webhook_deliveries WHERE id IN \\(" + deliveries + "\\)").
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM outbox WHERE member_id IN \\(" + members + "\\)").
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 6))
+	mock.ExpectExec("DELETE FROM members WHERE deleted_at < \\$1").
+		WithArgs(cutoff).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	// Act
+	purged, err := repo.PurgeDeletedMembers(context.Background(), cutoff)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 3, purged)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordValidation(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	at := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE members SET validation_status = \\$1, validation_reason = \\$2, validated_at = \\$3 WHERE id = \\$4 AND version = \\$5 AND deleted_at IS NULL RETURNING id, .*, validated_at").
+		WithArgs("rejected", "role: must not be empty", at, 1, 2).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John Doe", "employee", "", 5, pq.Array([]string{}), 2, "rejected", "role: must not be empty", at, nil))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs("member.validated", 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// Act
+	err := repo.RecordValidation(context.Background(), 1, 2, models.Validation{
+		Status: models.ValidationRejected,
+		Reason: "role: must not be empty",
+		At:     at,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordValidationOfChangedMember(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE members SET validation_status = \\$1, validation_reason = \\$2, validated_at = \\$3 WHERE id = \\$4 AND version = \\$5").
+		WithArgs("valid", nil, sqlmock.AnyArg(), 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM members WHERE id = \\$1 AND deleted_at IS NULL\\)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	// Act
+	err := repo.RecordValidation(context.Background(), 1, 2, models.Validation{Status: models.ValidationValid, At: time.Now()})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevalidateMember(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE members SET validation_status = \\$1, validation_reason = NULL, validated_at = NULL WHERE id = \\$2 AND deleted_at IS NULL RETURNING id, .*, validated_at").
+		WithArgs("pending", 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John Doe", "contractor", "", 6, pq.Array([]string{}), 2, "pending", nil, nil, nil))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs("member.revalidated", 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// Act
+	member, err := repo.RevalidateMember(context.Background(), 1)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.ValidationPending, member.ValidationStatus)
+	assert.Nil(t, member.ValidatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevalidateMissingMember(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE members SET validation_status = \\$1").
+		WithArgs("pending", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	// Act
+	_, err := repo.RevalidateMember(context.Background(), 1)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListMembersByAnyTag(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM members WHERE deleted_at IS NULL AND tags && \\$1").
+		WithArgs(pq.Array([]string{"go", "sql"})).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT id, name, type, role, duration, tags, version, validation_status, validation_reason, validated_at, deleted_at FROM members WHERE deleted_at IS NULL AND tags && \\$1 ORDER BY id").
+		WithArgs(pq.Array([]string{"go", "sql"}), 11, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}))
 
 	// Act
-	err := repo.DeleteMember(1)
+	list, err := repo.ListMembers(context.Background(), ListOptions{Limit: 10, Tags: []string{"go", "sql"}})
 
 	// Assert
 	assert.NoError(t, err)
+	assert.Empty(t, list.Members)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
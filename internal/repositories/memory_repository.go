@@ -0,0 +1,528 @@
+package repositories
+
+import (
+	"codelit/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryRepository is a MemberRepository kept in memory, with the same behavior
+// as DBRepository. It is safe for concurrent use and meant for tests and demos.
+type MemoryRepository struct {
+	mu      sync.RWMutex
+	members map[int]*models.Member
+	lastID  int
+	audit   []*models.AuditEntry
+	now     func() time.Time
+}
+
+type MemoryOption func(*MemoryRepository)
+
+// WithMemoryClock sets the clock dating the audit entries, time.Now by default.
+func WithMemoryClock(now func() time.Time) MemoryOption {
+	return func(r *MemoryRepository) {
+		r.now = now
+	}
+}
+
+func NewMemoryRepository(opts ...MemoryOption) *MemoryRepository {
+	r := &MemoryRepository{
+		members: map[int]*models.Member{},
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// record adds the entry of a change to the audit trail, like writeAudit, and
+// returns it.
+func (r *MemoryRepository) record(ctx context.Context, action string, before, after *models.Member) *models.AuditEntry {
+	entry := newAuditEntry(ctx, action, before, after, r.now())
+	entry.ID = int64(len(r.audit) + 1)
+	r.audit = append(r.audit, entry)
+	return entry
+}
+
+// copyMember returns a copy of the member that shares nothing with it, so that
+// callers never hold references to the stored members.
+func copyMember(member *models.Member) *models.Member {
+	c := *member
+	c.Tags = append([]string{}, member.Tags...)
+	if member.ValidatedAt != nil {
+		at := *member.ValidatedAt
+		c.ValidatedAt = &at
+	}
+	if member.DeletedAt != nil {
+		at := *member.DeletedAt
+		c.DeletedAt = &at
+	}
+	return &c
+}
+
+// sorted returns copies of the members in id order, without the deleted ones
+// unless told otherwise.
+func (r *MemoryRepository) sorted(includeDeleted bool) []*models.Member {
+	members := []*models.Member{}
+	for _, member := range r.members {
+		if member.DeletedAt == nil || includeDeleted {
+			members = append(members, copyMember(member))
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].ID < members[j].ID
+	})
+	return members
+}
+
+func (r *MemoryRepository) GetAllMembers(ctx context.Context) ([]*models.Member, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.sorted(false), nil
+}
+
+func (r *MemoryRepository) ListMembers(ctx context.Context, opts ListOptions) (*models.MemberList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	members := []*models.Member{}
+	for _, member := range r.sorted(opts.IncludeDeleted) {
+		if matches(member, opts) {
+			members = append(members, member)
+		}
+	}
+	list := &models.MemberList{Total: len(members), Limit: opts.Limit, Offset: opts.Offset}
+
+	var ks *keyset
+	if opts.Cursor != "" {
+		var err error
+		if ks, err = resolveCursor(opts.Cursor, opts.Sort); err != nil {
+			return nil, err
+		}
+		opts.Sort = ks.sort
+		opts.Offset = 0
+		list.Offset = 0
+	}
+
+	order := withIDTiebreak(opts.Sort)
+	sort.Slice(members, func(i, j int) bool {
+		for _, field := range order {
+			c := compareColumn(members[i], field.Column, sortValue(members[j], field.Column))
+			if c != 0 {
+				return (c < 0) != field.Desc
+			}
+		}
+		return false
+	})
+
+	page := []*models.Member{}
+	for _, member := range members {
+		if ks != nil && !ks.after(member) {
+			continue
+		}
+		page = append(page, member)
+	}
+	if opts.Offset < len(page) {
+		page = page[opts.Offset:]
+	} else {
+		page = page[:0]
+	}
+	if len(page) > opts.Limit+1 {
+		page = page[:opts.Limit+1]
+	}
+
+	list.Members = page
+	paginate(list, opts)
+	return list, nil
+}
+
+// matches applies the filters of ListMembers.
+func matches(member *models.Member, opts ListOptions) bool {
+	if opts.Type != "" && member.Type != opts.Type {
+		return false
+	}
+	if opts.Role != "" && member.Role != opts.Role {
+		return false
+	}
+	if opts.Status != "" && member.ValidationStatus != opts.Status {
+		return false
+	}
+	if opts.Name != "" && !strings.Contains(strings.ToLower(member.Name), strings.ToLower(opts.Name)) {
+		return false
+	}
+	if len(opts.Tags) == 0 {
+		return true
+	}
+
+	has := map[string]bool{}
+	for _, tag := range member.Tags {
+		has[tag] = true
+	}
+	found := 0
+	for _, tag := range opts.Tags {
+		if has[models.FoldTag(tag)] {
+			found++
+		}
+	}
+	if opts.TagMatch == TagMatchAll {
+		return found == len(opts.Tags)
+	}
+	return found > 0
+}
+
+// after tells whether the member comes after the cursor in the listing order.
+func (ks *keyset) after(member *models.Member) bool {
+	for i, field := range ks.fields {
+		c := compareColumn(member, field.Column, ks.values[i])
+		if c != 0 {
+			return (c > 0) != field.Desc
+		}
+	}
+	return false
+}
+
+// compareColumn compares a member column with a value as returned by sortValue.
+func compareColumn(member *models.Member, column string, value string) int {
+	if column == "id" || column == "duration" {
+		n, _ := strconv.Atoi(value)
+		m, _ := strconv.Atoi(sortValue(member, column))
+		switch {
+		case m < n:
+			return -1
+		case m > n:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(sortValue(member, column), value)
+}
+
+func (r *MemoryRepository) GetMemberByID(ctx context.Context, id int) (*models.Member, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	member, ok := r.members[id]
+	if !ok || member.DeletedAt != nil {
+		return nil, notFound("member")
+	}
+	return copyMember(member), nil
+}
+
+func (r *MemoryRepository) CreateMember(ctx context.Context, member *models.Member) error {
+	var err error
+	if member.Tags, err = NormalizeTags(member.Tags); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	member.ID = r.lastID
+	member.Version = 1
+	member.ValidationStatus, member.ValidationReason, member.ValidatedAt, member.DeletedAt = models.ValidationPending, "", nil, nil
+	r.members[member.ID] = copyMember(member)
+	r.record(ctx, models.AuditCreated, nil, member)
+	return nil
+}
+
+// stored returns the member to change, checking its version like DBRepository
+// does. Deleted members are missing.
+func (r *MemoryRepository) stored(id int, version int) (*models.Member, error) {
+	member, ok := r.members[id]
+	if !ok || member.DeletedAt != nil {
+		return nil, notFound("member")
+	}
+	if version > 0 && member.Version != version {
+		return nil, &Error{Kind: ErrVersionMismatch, Err: errors.New("member was modified by someone else")}
+	}
+	return member, nil
+}
+
+func (r *MemoryRepository) UpdateMember(ctx context.Context, member *models.Member) error {
+	var err error
+	if member.Tags, err = NormalizeTags(member.Tags); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.stored(member.ID, member.Version)
+	if err != nil {
+		return err
+	}
+	member.Version = stored.Version + 1
+	member.ValidationStatus, member.ValidationReason, member.ValidatedAt = models.ValidationPending, "", nil
+	member.DeletedAt = nil
+	r.members[member.ID] = copyMember(member)
+	r.record(ctx, models.AuditUpdated, stored, member)
+	return nil
+}
+
+func (r *MemoryRepository) UpdateMemberFields(ctx context.Context, member *models.Member, fields []string) error {
+	var err error
+	if member.Tags, err = NormalizeTags(member.Tags); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if _, ok := memberColumnValue(member, field); !ok {
+			return fmt.Errorf("unknown member field %q", field)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.stored(member.ID, member.Version)
+	if err != nil {
+		return err
+	}
+	updated := copyMember(stored)
+	for _, field := range fields {
+		switch field {
+		case "name":
+			updated.Name = member.Name
+		case "type":
+			updated.Type = member.Type
+		case "role":
+			updated.Role = member.Role
+		case "duration":
+			updated.Duration = member.Duration
+		case "tags":
+			updated.Tags = append([]string{}, member.Tags...)
+		}
+	}
+	updated.Version++
+	updated.ValidationStatus, updated.ValidationReason, updated.ValidatedAt = models.ValidationPending, "", nil
+	member.Version = updated.Version
+	member.ValidationStatus, member.ValidationReason, member.ValidatedAt = updated.ValidationStatus, updated.ValidationReason, updated.ValidatedAt
+	r.members[member.ID] = updated
+	r.record(ctx, models.AuditUpdated, stored, updated)
+	return nil
+}
+
+func (r *MemoryRepository) DeleteMember(ctx context.Context, id int, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.stored(id, version)
+	if err != nil {
+		return err
+	}
+	deleted := copyMember(stored)
+	at := r.record(ctx, models.AuditDeleted, stored, nil).At
+	deleted.DeletedAt = &at
+	r.members[id] = deleted
+	return nil
+}
+
+func (r *MemoryRepository) RestoreMember(ctx context.Context, id int, version int) (*models.Member, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member, ok := r.members[id]
+	if !ok {
+		return nil, notFound("member")
+	}
+	if member.DeletedAt == nil {
+		return nil, &Error{Kind: ErrConflict, Err: errors.New("member is not deleted")}
+	}
+	if version > 0 && member.Version != version {
+		return nil, &Error{Kind: ErrVersionMismatch, Err: errors.New("member was modified by someone else")}
+	}
+	restored := copyMember(member)
+	restored.DeletedAt = nil
+	restored.Version++
+	r.members[id] = restored
+	r.record(ctx, models.AuditRestored, nil, restored)
+	return copyMember(restored), nil
+}
+
+func (r *MemoryRepository) PurgeDeletedMembers(ctx context.Context, deletedBefore time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := 0
+	for id, member := range r.members {
+		if member.DeletedAt != nil && member.DeletedAt.Before(deletedBefore) {
+			delete(r.members, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (r *MemoryRepository) RecordValidation(ctx context.Context, id int, version int, validation models.Validation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member, err := r.stored(id, version)
+	if err != nil {
+		return err
+	}
+	updated := copyMember(member)
+	at := validation.At
+	updated.ValidationStatus, updated.ValidationReason, updated.ValidatedAt = validation.Status, validation.Reason, &at
+	r.members[id] = updated
+	return nil
+}
+
+func (r *MemoryRepository) RevalidateMember(ctx context.Context, id int) (*models.Member, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member, err := r.stored(id, 0)
+	if err != nil {
+		return nil, err
+	}
+	updated := copyMember(member)
+	updated.ValidationStatus, updated.ValidationReason, updated.ValidatedAt = models.ValidationPending, "", nil
+	r.members[id] = updated
+	return copyMember(updated), nil
+}
+
+func (r *MemoryRepository) ListTags(ctx context.Context) ([]models.TagCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := map[string]int{}
+	for _, member := range r.sorted(false) {
+		for _, tag := range member.Tags {
+			counts[tag]++
+		}
+	}
+	tags := []models.TagCount{}
+	for tag, count := range counts {
+		tags = append(tags, models.TagCount{Tag: tag, Members: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+func (r *MemoryRepository) MergeTags(ctx context.Context, sources []string, target string) (int, error) {
+	sources, err := NormalizeTags(sources)
+	if err != nil {
+		return 0, err
+	}
+	if target, err = NormalizeTag(target); err != nil {
+		return 0, err
+	}
+	merged := map[string]bool{}
+	for _, tag := range sources {
+		merged[tag] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	// In id order, like DBRepository writes the audit entries
+	for _, member := range r.sorted(false) {
+		id := member.ID
+		tags := []string{}
+		seen := map[string]bool{}
+		changed := false
+		for _, tag := range member.Tags {
+			if merged[tag] {
+				tag = target
+				changed = true
+			}
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		if changed {
+			updated := copyMember(member)
+			updated.Tags = tags
+			updated.Version++
+			updated.ValidationStatus, updated.ValidationReason, updated.ValidatedAt = models.ValidationPending, "", nil
+			r.members[id] = updated
+			r.record(ctx, models.AuditUpdated, member, updated)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryRepository) AddMemberTag(ctx context.Context, id int, tag string) (*models.Member, error) {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member, err := r.stored(id, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range member.Tags {
+		if existing == tag {
+			return copyMember(member), nil
+		}
+	}
+	updated := copyMember(member)
+	updated.Tags = append(updated.Tags, tag)
+	updated.Version++
+	updated.ValidationStatus, updated.ValidationReason, updated.ValidatedAt = models.ValidationPending, "", nil
+	r.members[id] = updated
+	r.record(ctx, models.AuditUpdated, member, updated)
+	return copyMember(updated), nil
+}
+
+func (r *MemoryRepository) RemoveMemberTag(ctx context.Context, id int, tag string) (*models.Member, error) {
+	tag = models.FoldTag(tag)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member, err := r.stored(id, 0)
+	if err != nil {
+		return nil, err
+	}
+	updated := copyMember(member)
+	updated.Tags = []string{}
+	for _, existing := range member.Tags {
+		if existing != tag {
+			updated.Tags = append(updated.Tags, existing)
+		}
+	}
+	if len(updated.Tags) == len(member.Tags) {
+		return copyMember(member), nil
+	}
+	updated.Version++
+	updated.ValidationStatus, updated.ValidationReason, updated.ValidatedAt = models.ValidationPending, "", nil
+	r.members[id] = updated
+	r.record(ctx, models.AuditUpdated, member, updated)
+	return copyMember(updated), nil
+}
+
+func (r *MemoryRepository) ListAudit(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []*models.AuditEntry{}
+	for i := len(r.audit) - 1; i >= 0 && (filter.Limit == 0 || len(entries) < filter.Limit); i-- {
+		if filter.matches(r.audit[i]) {
+			entry := *r.audit[i]
+			entries = append(entries, &entry)
+		}
+	}
+	return entries, nil
+}
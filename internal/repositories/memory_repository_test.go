@@ -0,0 +1,13 @@
+package repositories_test
+
+import (
+	"codelit/internal/repositories"
+	"codelit/internal/repositories/repositorytest"
+	"testing"
+)
+
+func TestMemoryRepositoryConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositories.MemberRepository {
+		return repositories.NewMemoryRepository()
+	})
+}
@@ -0,0 +1,658 @@
+// Package repositorytest holds the conformance suite every MemberRepository
+// implementation must pass.
+package repositorytest
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/repositories"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the conformance suite. newRepo must return an empty repository each
+// time it is called.
+func Run(t *testing.T, newRepo func(t *testing.T) repositories.MemberRepository) {
+	tests := map[string]func(t *testing.T, repo repositories.MemberRepository){
+		"CreateAssignsIDsAndVersions":   testCreateAssignsIDsAndVersions,
+		"GetReturnsNotFound":            testGetReturnsNotFound,
+		"UpdateChecksVersions":          testUpdateChecksVersions,
+		"UpdateFieldsOnlyTouchesFields": testUpdateFieldsOnlyTouchesFields,
+		"DeleteChecksVersions":          testDeleteChecksVersions,
+		"TagsAreNormalized":             testTagsAreNormalized,
+		"ListFiltersAndSorts":           testListFiltersAndSorts,
+		"ListPagesWithCursor":           testListPagesWithCursor,
+		"ListPagesWithMixedSort":        testListPagesWithMixedSort,
+		"ListRejectsForeignCursor":      testListRejectsForeignCursor,
+		"MemberTags":                    testMemberTags,
+		"MergeTags":                     testMergeTags,
+		"ConcurrentCreates":             testConcurrentCreates,
+		"RecordsValidations":            testRecordsValidations,
+		"ChangesResetValidation":        testChangesResetValidation,
+		"ListFiltersByValidation":       testListFiltersByValidation,
+		"AuditRecordsChanges":           testAuditRecordsChanges,
+		"AuditFiltersAndPages":          testAuditFiltersAndPages,
+		"DeleteHidesMembers":            testDeleteHidesMembers,
+		"RestoreMember":                 testRestoreMember,
+		"PurgeDeletedMembers":           testPurgeDeletedMembers,
+	}
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			test(t, newRepo(t))
+		})
+	}
+}
+
+func create(t *testing.T, repo repositories.MemberRepository, member *models.Member) *models.Member {
+	t.Helper()
+	require.NoError(t, repo.CreateMember(context.Background(), member))
+	return member
+}
+
+func employee(name, role string, tags ...string) *models.Member {
+	return &models.Member{Name: name, Type: models.MemberTypeEmployee, Role: role, Tags: tags}
+}
+
+func contractor(name string, duration int, tags ...string) *models.Member {
+	return &models.Member{Name: name, Type: models.MemberTypeContractor, Duration: duration, Tags: tags}
+}
+
+func names(members []*models.Member) []string {
+	result := []string{}
+	for _, member := range members {
+		result = append(result, member.Name)
+	}
+	return result
+}
+
+func testCreateAssignsIDsAndVersions(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	first := create(t, repo, employee("Alice", "Engineer", "go"))
+	second := create(t, repo, contractor("Bob", 6))
+
+	assert.NotZero(t, first.ID)
+	assert.Greater(t, second.ID, first.ID)
+	assert.Equal(t, 1, first.Version)
+
+	stored, err := repo.GetMemberByID(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first, stored)
+
+	all, err := repo.GetAllMembers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Bob"}, names(all))
+}
+
+func testGetReturnsNotFound(t *testing.T, repo repositories.MemberRepository) {
+	_, err := repo.GetMemberByID(context.Background(), 4242)
+
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+func testUpdateChecksVersions(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	member := create(t, repo, employee("Alice", "Engineer"))
+
+	update := employee("Alice", "Tech Lead")
+	update.ID = member.ID
+	update.Version = member.Version
+	require.NoError(t, repo.UpdateMember(ctx, update))
+	assert.Equal(t, 2, update.Version)
+
+	stale := employee("Alice", "Manager")
+	stale.ID = member.ID
+	stale.Version = 1
+	assert.ErrorIs(t, repo.UpdateMember(ctx, stale), repositories.ErrVersionMismatch)
+
+	unconditional := employee("Alice", "Manager")
+	unconditional.ID = member.ID
+	require.NoError(t, repo.UpdateMember(ctx, unconditional))
+	assert.Equal(t, 3, unconditional.Version)
+
+	missing := employee("Nobody", "Engineer")
+	missing.ID = 4242
+	assert.ErrorIs(t, repo.UpdateMember(ctx, missing), repositories.ErrNotFound)
+
+	stored, err := repo.GetMemberByID(ctx, member.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Manager", stored.Role)
+	assert.Equal(t, 3, stored.Version)
+}
+
+func testUpdateFieldsOnlyTouchesFields(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	member := create(t, repo, employee("Alice", "Engineer", "go"))
+
+	patch := employee("Changed", "Tech Lead", "Go", "sql")
+	patch.ID = member.ID
+	patch.Version = member.Version
+	require.NoError(t, repo.UpdateMemberFields(ctx, patch, []string{"role", "tags"}))
+	assert.Equal(t, 2, patch.Version)
+
+	stored, err := repo.GetMemberByID(ctx, member.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", stored.Name)
+	assert.Equal(t, "Tech Lead", stored.Role)
+	assert.Equal(t, []string{"go", "sql"}, stored.Tags)
+
+	patch.Version = 1
+	assert.ErrorIs(t, repo.UpdateMemberFields(ctx, patch, []string{"role"}), repositories.ErrVersionMismatch)
+}
+
+func testDeleteChecksVersions(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	member := create(t, repo, contractor("Bob", 6))
+
+	assert.ErrorIs(t, repo.DeleteMember(ctx, member.ID, 7), repositories.ErrVersionMismatch)
+	require.NoError(t, repo.DeleteMember(ctx, member.ID, member.Version))
+	assert.ErrorIs(t, repo.DeleteMember(ctx, member.ID, 0), repositories.ErrNotFound)
+
+	_, err := repo.GetMemberByID(ctx, member.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+func testTagsAreNormalized(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	member := create(t, repo, employee("Alice", "Engineer", " Go", "SQL", "go"))
+	assert.Equal(t, []string{"go", "sql"}, member.Tags)
+
+	stored, err := repo.GetMemberByID(ctx, member.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "sql"}, stored.Tags)
+
+	untagged := create(t, repo, contractor("Bob", 6))
+	stored, err = repo.GetMemberByID(ctx, untagged.ID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Tags)
+
+	err = repo.CreateMember(ctx, employee("Carol", "Engineer", " "))
+	assert.ErrorIs(t, err, repositories.ErrValidation)
+}
+
+func testListFiltersAndSorts(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	create(t, repo, employee("Alice", "Engineer", "go", "sql"))
+	create(t, repo, contractor("Bob", 6, "go"))
+	create(t, repo, employee("Carol", "Manager", "sql"))
+	create(t, repo, contractor("Dave", 3))
+
+	sort, err := repositories.ParseSort("-duration,name")
+	require.NoError(t, err)
+	list, err := repo.ListMembers(ctx, repositories.ListOptions{Limit: 10, Sort: sort})
+	require.NoError(t, err)
+	assert.Equal(t, 4, list.Total)
+	assert.Equal(t, []string{"Bob", "Dave", "Alice", "Carol"}, names(list.Members))
+	assert.Empty(t, list.NextCursor)
+
+	list, err = repo.ListMembers(ctx, repositories.ListOptions{Limit: 10, Type: models.MemberTypeEmployee})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Carol"}, names(list.Members))
+
+	list, err = repo.ListMembers(ctx, repositories.ListOptions{Limit: 10, Role: "Manager"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Carol"}, names(list.Members))
+
+	list, err = repo.ListMembers(ctx, repositories.ListOptions{Limit: 10, Name: "A"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Carol", "Dave"}, names(list.Members))
+
+	list, err = repo.ListMembers(ctx, repositories.ListOptions{Limit: 10, Tags: []string{"GO", "sql"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names(list.Members))
+
+	list, err = repo.ListMembers(ctx, repositories.ListOptions{Limit: 10, Tags: []string{"go", "sql"}, TagMatch: repositories.TagMatchAll})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice"}, names(list.Members))
+
+	list, err = repo.ListMembers(ctx, repositories.ListOptions{Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 4, list.Total)
+	assert.Equal(t, []string{"Bob", "Carol"}, names(list.Members))
+}
+
+func testListPagesWithCursor(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	for _, name := range []string{"Dave", "Alice", "Carol", "Bob", "Alice"} {
+		create(t, repo, contractor(name, 1))
+	}
+	sort, err := repositories.ParseSort("-name")
+	require.NoError(t, err)
+
+	seen := []string{}
+	opts := repositories.ListOptions{Limit: 2, Sort: sort}
+	for pages := 0; pages < 5; pages++ {
+		list, err := repo.ListMembers(ctx, opts)
+		require.NoError(t, err)
+		assert.Equal(t, 5, list.Total)
+		seen = append(seen, names(list.Members)...)
+		if list.NextCursor == "" {
+			break
+		}
+		// The cursor keeps working when the sort is not repeated
+		opts = repositories.ListOptions{Limit: 2, Cursor: list.NextCursor}
+	}
+
+	assert.Equal(t, []string{"Dave", "Carol", "Bob", "Alice", "Alice"}, seen)
+}
+
+func testListPagesWithMixedSort(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	for _, member := range []*models.Member{
+		contractor("Dave", 3), contractor("Bob", 6), contractor("Alice", 3), contractor("Carol", 6), contractor("Alice", 3),
+	} {
+		create(t, repo, member)
+	}
+	sort, err := repositories.ParseSort("-duration,name")
+	require.NoError(t, err)
+
+	seen := []string{}
+	opts := repositories.ListOptions{Limit: 2, Sort: sort}
+	for pages := 0; pages < 5; pages++ {
+		list, err := repo.ListMembers(ctx, opts)
+		require.NoError(t, err)
+		seen = append(seen, names(list.Members)...)
+		if list.NextCursor == "" {
+			break
+		}
+		opts = repositories.ListOptions{Limit: 2, Sort: sort, Cursor: list.NextCursor}
+	}
+
+	assert.Equal(t, []string{"Bob", "Carol", "Alice", "Alice", "Dave"}, seen, "every page but the last has a cursor")
+}
+
+func testListRejectsForeignCursor(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	create(t, repo, contractor("Alice", 1))
+	create(t, repo, contractor("Bob", 1))
+
+	list, err := repo.ListMembers(ctx, repositories.ListOptions{Limit: 1})
+	require.NoError(t, err)
+	require.NotEmpty(t, list.NextCursor)
+
+	sort, err := repositories.ParseSort("name")
+	require.NoError(t, err)
+	_, err = repo.ListMembers(ctx, repositories.ListOptions{Limit: 1, Sort: sort, Cursor: list.NextCursor})
+	assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+
+	_, err = repo.ListMembers(ctx, repositories.ListOptions{Limit: 1, Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, repositories.ErrInvalidCursor)
+}
+
+func testMemberTags(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	member := create(t, repo, employee("Alice", "Engineer", "go"))
+
+	updated, err := repo.AddMemberTag(ctx, member.ID, " SQL ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "sql"}, updated.Tags)
+	assert.Equal(t, 2, updated.Version)
+
+	unchanged, err := repo.AddMemberTag(ctx, member.ID, "go")
+	require.NoError(t, err)
+	assert.Equal(t, updated, unchanged)
+
+	updated, err = repo.RemoveMemberTag(ctx, member.ID, "GO")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sql"}, updated.Tags)
+	assert.Equal(t, 3, updated.Version)
+
+	unchanged, err = repo.RemoveMemberTag(ctx, member.ID, "go")
+	require.NoError(t, err)
+	assert.Equal(t, updated, unchanged)
+
+	_, err = repo.AddMemberTag(ctx, 4242, "go")
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = repo.RemoveMemberTag(ctx, 4242, "go")
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = repo.AddMemberTag(ctx, member.ID, "")
+	assert.ErrorIs(t, err, repositories.ErrValidation)
+}
+
+func testMergeTags(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	alice := create(t, repo, employee("Alice", "Engineer", "golang", "sql", "go"))
+	bob := create(t, repo, contractor("Bob", 6, "go-lang"))
+	carol := create(t, repo, contractor("Carol", 6, "sql"))
+
+	count, err := repo.MergeTags(ctx, []string{"Golang", "go-lang"}, "go")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	stored, err := repo.GetMemberByID(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "sql"}, stored.Tags)
+	assert.Equal(t, 2, stored.Version)
+	stored, err = repo.GetMemberByID(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go"}, stored.Tags)
+	stored, err = repo.GetMemberByID(ctx, carol.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.Version)
+
+	tags, err := repo.ListTags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TagCount{{Tag: "go", Members: 2}, {Tag: "sql", Members: 2}}, tags)
+}
+
+func testConcurrentCreates(t *testing.T, repo repositories.MemberRepository) {
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.CreateMember(context.Background(), contractor("Bob", 1)))
+		}()
+	}
+	wg.Wait()
+
+	all, err := repo.GetAllMembers(context.Background())
+	require.NoError(t, err)
+	ids := map[int]bool{}
+	for _, member := range all {
+		ids[member.ID] = true
+	}
+	assert.Len(t, ids, 20)
+}
+
+func testRecordsValidations(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	member := create(t, repo, employee("Alice", "Engineer"))
+	assert.Equal(t, models.ValidationPending, member.ValidationStatus)
+
+	at := time.Now().UTC()
+	rejected := models.Validation{Status: models.ValidationRejected, Reason: "role: is not known", At: at}
+	require.NoError(t, repo.RecordValidation(ctx, member.ID, member.Version, rejected))
+	assert.ErrorIs(t, repo.RecordValidation(ctx, member.ID, 7, rejected), repositories.ErrVersionMismatch)
+	assert.ErrorIs(t, repo.RecordValidation(ctx, 4242, 1, rejected), repositories.ErrNotFound)
+
+	// The verdict leaves the version alone, so that the ETag the client holds
+	// still matches for its next write
+	stored, err := repo.GetMemberByID(ctx, member.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.Version)
+	assert.Equal(t, models.ValidationRejected, stored.ValidationStatus)
+	assert.Equal(t, "role: is not known", stored.ValidationReason)
+	require.NotNil(t, stored.ValidatedAt)
+	assert.WithinDuration(t, at, *stored.ValidatedAt, time.Millisecond)
+
+	revalidated, err := repo.RevalidateMember(ctx, member.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ValidationPending, revalidated.ValidationStatus)
+	assert.Empty(t, revalidated.ValidationReason)
+	assert.Nil(t, revalidated.ValidatedAt)
+	assert.Equal(t, 1, revalidated.Version)
+
+	_, err = repo.RevalidateMember(ctx, 4242)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+}
+
+func testChangesResetValidation(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	valid := models.Validation{Status: models.ValidationValid, At: time.Now().UTC()}
+	member := create(t, repo, employee("Alice", "Engineer", "go"))
+
+	// The verdict of the created member comes after an update: it no longer
+	// applies, and the update is pending until its own verdict
+	update := employee("Alice", "Tech Lead", "go")
+	update.ID = member.ID
+	update.ValidationStatus = models.ValidationValid
+	require.NoError(t, repo.UpdateMember(ctx, update))
+	assert.Equal(t, models.ValidationPending, update.ValidationStatus)
+	assert.ErrorIs(t, repo.RecordValidation(ctx, member.ID, member.Version, valid), repositories.ErrVersionMismatch)
+	require.NoError(t, repo.RecordValidation(ctx, update.ID, update.Version, valid))
+	stored, err := repo.GetMemberByID(ctx, member.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ValidationValid, stored.ValidationStatus)
+
+	changes := []struct {
+		name   string
+		change func() error
+	}{
+		{"update fields", func() error {
+			return repo.UpdateMemberFields(ctx, &models.Member{ID: member.ID, Role: "Manager"}, []string{"role"})
+		}},
+		{"add tag", func() error {
+			_, err := repo.AddMemberTag(ctx, member.ID, "rust")
+			return err
+		}},
+		{"remove tag", func() error {
+			_, err := repo.RemoveMemberTag(ctx, member.ID, "rust")
+			return err
+		}},
+		{"merge tags", func() error {
+			_, err := repo.MergeTags(ctx, []string{"go"}, "golang")
+			return err
+		}},
+	}
+	for _, tt := range changes {
+		stored, err := repo.GetMemberByID(ctx, member.ID)
+		require.NoError(t, err)
+		require.NoError(t, repo.RecordValidation(ctx, member.ID, stored.Version, valid), tt.name)
+
+		require.NoError(t, tt.change(), tt.name)
+
+		stored, err = repo.GetMemberByID(ctx, member.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.ValidationPending, stored.ValidationStatus, tt.name)
+		assert.Empty(t, stored.ValidationReason, tt.name)
+		assert.Nil(t, stored.ValidatedAt, tt.name)
+	}
+}
+
+func testListFiltersByValidation(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	alice := create(t, repo, employee("Alice", "Engineer"))
+	create(t, repo, contractor("Bob", 6))
+	carol := create(t, repo, employee("Carol", "Manager"))
+	valid := models.Validation{Status: models.ValidationValid, At: time.Now()}
+	require.NoError(t, repo.RecordValidation(ctx, alice.ID, alice.Version, valid))
+	require.NoError(t, repo.RecordValidation(ctx, carol.ID, carol.Version, valid))
+
+	list, err := repo.ListMembers(ctx, repositories.ListOptions{Limit: 10, Status: models.ValidationValid})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Carol"}, names(list.Members))
+	assert.Equal(t, 2, list.Total)
+
+	list, err = repo.ListMembers(ctx, repositories.ListOptions{Limit: 10, Status: models.ValidationPending})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bob"}, names(list.Members))
+}
+
+// changes returns the changes of an audit entry as JSON.
+func changes(t *testing.T, entry *models.AuditEntry) string {
+	t.Helper()
+	data, err := json.Marshal(entry.Changes)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func testAuditRecordsChanges(t *testing.T, repo repositories.MemberRepository) {
+	ctx := repositories.ContextWithActor(context.Background(), models.Actor{Name: "alice@example.com", RequestID: "req-1"})
+	member := create(t, repo, employee("Alice", "Engineer", "go"))
+	other := create(t, repo, contractor("Bob", 6))
+
+	update := employee("Alice", "Tech Lead", "go")
+	update.ID = member.ID
+	require.NoError(t, repo.UpdateMember(ctx, update))
+	stale := employee("Alice", "Manager")
+	stale.ID, stale.Version = member.ID, 1
+	require.ErrorIs(t, repo.UpdateMember(ctx, stale), repositories.ErrVersionMismatch)
+	patch := contractor("Ignored", 3)
+	patch.ID = member.ID
+	require.NoError(t, repo.UpdateMemberFields(ctx, patch, []string{"duration"}))
+	_, err := repo.AddMemberTag(ctx, member.ID, "SQL")
+	require.NoError(t, err)
+	_, err = repo.RemoveMemberTag(ctx, member.ID, "rust")
+	require.NoError(t, err)
+	_, err = repo.MergeTags(ctx, []string{"sql"}, "postgres")
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteMember(ctx, member.ID, 0))
+
+	entries, err := repo.ListAudit(context.Background(), repositories.AuditFilter{MemberID: member.ID})
+	require.NoError(t, err)
+	require.Len(t, entries, 6, "failed and empty changes are not recorded")
+	want := []struct {
+		action  string
+		actor   string
+		version int
+		changes string
+	}{
+		{models.AuditDeleted, "alice@example.com", 5,
+			`{"duration":{"before":3,"after":null},"name":{"before":"Alice","after":null},"role":{"before":"Tech Lead","after":null},` +
+				`"tags":{"before":["go","postgres"],"after":null},"type":{"before":"employee","after":null}}`},
+		{models.AuditUpdated, "alice@example.com", 5, `{"tags":{"before":["go","sql"],"after":["go","postgres"]}}`},
+		{models.AuditUpdated, "alice@example.com", 4, `{"tags":{"before":["go"],"after":["go","sql"]}}`},
+		{models.AuditUpdated, "alice@example.com", 3, `{"duration":{"before":null,"after":3}}`},
+		{models.AuditUpdated, "alice@example.com", 2, `{"role":{"before":"Engineer","after":"Tech Lead"}}`},
+		{models.AuditCreated, repositories.SystemActor, 1,
+			`{"name":{"before":null,"after":"Alice"},"role":{"before":null,"after":"Engineer"},"tags":{"before":null,"after":["go"]},"type":{"before":null,"after":"employee"}}`},
+	}
+	for i, entry := range entries {
+		assert.Equal(t, member.ID, entry.MemberID)
+		assert.Equal(t, want[i].action, entry.Action)
+		assert.Equal(t, want[i].actor, entry.Actor)
+		assert.Equal(t, want[i].version, entry.Version)
+		assert.JSONEq(t, want[i].changes, changes(t, entry))
+		assert.False(t, entry.At.IsZero())
+		if i > 0 {
+			assert.Less(t, entry.ID, entries[i-1].ID)
+		}
+	}
+	assert.Equal(t, "req-1", entries[0].RequestID)
+	assert.Empty(t, entries[5].RequestID)
+
+	others, err := repo.ListAudit(context.Background(), repositories.AuditFilter{MemberID: other.ID})
+	require.NoError(t, err)
+	assert.Len(t, others, 1)
+}
+
+func testAuditFiltersAndPages(t *testing.T, repo repositories.MemberRepository) {
+	start := time.Now().Add(-time.Minute)
+	alice := repositories.ContextWithActor(context.Background(), models.Actor{Name: "alice@example.com"})
+	bob := repositories.ContextWithActor(context.Background(), models.Actor{Name: "bob@example.com"})
+	for i, ctx := range []context.Context{alice, bob, alice, alice} {
+		require.NoError(t, repo.CreateMember(ctx, contractor("Member", i+1)))
+	}
+	list := func(filter repositories.AuditFilter) []int {
+		t.Helper()
+		entries, err := repo.ListAudit(context.Background(), filter)
+		require.NoError(t, err)
+		durations := []int{}
+		for _, entry := range entries {
+			var duration int
+			require.NoError(t, json.Unmarshal(entry.Changes["duration"].After, &duration))
+			durations = append(durations, duration)
+		}
+		return durations
+	}
+
+	assert.Equal(t, []int{4, 3, 1}, list(repositories.AuditFilter{Actor: "alice@example.com"}))
+	assert.Equal(t, []int{}, list(repositories.AuditFilter{Actor: "carol@example.com"}))
+	assert.Equal(t, []int{4, 3, 2, 1}, list(repositories.AuditFilter{From: start, To: time.Now().Add(time.Minute)}))
+	assert.Equal(t, []int{}, list(repositories.AuditFilter{From: time.Now().Add(time.Minute)}))
+	assert.Equal(t, []int{}, list(repositories.AuditFilter{To: start}))
+
+	page, err := repo.ListAudit(context.Background(), repositories.AuditFilter{Limit: 3})
+	require.NoError(t, err)
+	require.Len(t, page, 3)
+	assert.Equal(t, []int{1}, list(repositories.AuditFilter{Before: page[2].ID, Limit: 3}))
+}
+
+func testDeleteHidesMembers(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	alice := create(t, repo, employee("Alice", "Engineer", "go"))
+	bob := create(t, repo, contractor("Bob", 6, "go", "sql"))
+	require.NoError(t, repo.DeleteMember(ctx, bob.ID, 0))
+
+	all, err := repo.GetAllMembers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice"}, names(all))
+	list, err := repo.ListMembers(ctx, repositories.ListOptions{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.Total)
+	assert.Equal(t, []string{"Alice"}, names(list.Members))
+	tags, err := repo.ListTags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TagCount{{Tag: "go", Members: 1}}, tags)
+
+	update := contractor("Bob", 3)
+	update.ID = bob.ID
+	assert.ErrorIs(t, repo.UpdateMember(ctx, update), repositories.ErrNotFound)
+	_, err = repo.AddMemberTag(ctx, bob.ID, "rust")
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = repo.RevalidateMember(ctx, bob.ID)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	merged, err := repo.MergeTags(ctx, []string{"sql"}, "postgres")
+	require.NoError(t, err)
+	assert.Zero(t, merged)
+
+	list, err = repo.ListMembers(ctx, repositories.ListOptions{Limit: 10, IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Total)
+	require.Equal(t, []string{"Alice", "Bob"}, names(list.Members))
+	assert.Nil(t, list.Members[0].DeletedAt)
+	require.NotNil(t, list.Members[1].DeletedAt)
+	assert.Equal(t, []string{"go", "sql"}, list.Members[1].Tags, "deleted members are left untouched")
+	assert.Equal(t, alice.ID, list.Members[0].ID)
+}
+
+func testRestoreMember(t *testing.T, repo repositories.MemberRepository) {
+	ctx := repositories.ContextWithActor(context.Background(), models.Actor{Name: "alice@example.com"})
+	member := create(t, repo, contractor("Bob", 6, "go"))
+	require.NoError(t, repo.DeleteMember(ctx, member.ID, 0))
+
+	_, err := repo.RestoreMember(ctx, member.ID, member.Version+1)
+	assert.ErrorIs(t, err, repositories.ErrVersionMismatch)
+	restored, err := repo.RestoreMember(ctx, member.ID, member.Version)
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", restored.Name)
+	assert.Equal(t, []string{"go"}, restored.Tags)
+	assert.Equal(t, member.Version+1, restored.Version, "the restore changes the ETag")
+	assert.Nil(t, restored.DeletedAt)
+
+	stored, err := repo.GetMemberByID(ctx, member.ID)
+	require.NoError(t, err)
+	assert.Equal(t, restored, stored)
+
+	_, err = repo.RestoreMember(ctx, member.ID, 0)
+	assert.ErrorIs(t, err, repositories.ErrConflict)
+	_, err = repo.RestoreMember(ctx, 4242, 0)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+
+	entries, err := repo.ListAudit(ctx, repositories.AuditFilter{MemberID: member.ID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.AuditRestored, entries[0].Action)
+	assert.Equal(t, "alice@example.com", entries[0].Actor)
+	assert.JSONEq(t, `{"duration":{"before":null,"after":6},"name":{"before":null,"after":"Bob"},`+
+		`"tags":{"before":null,"after":["go"]},"type":{"before":null,"after":"contractor"}}`, changes(t, entries[0]))
+}
+
+func testPurgeDeletedMembers(t *testing.T, repo repositories.MemberRepository) {
+	ctx := context.Background()
+	alice := create(t, repo, employee("Alice", "Engineer"))
+	bob := create(t, repo, contractor("Bob", 6))
+	require.NoError(t, repo.DeleteMember(ctx, bob.ID, 0))
+
+	purged, err := repo.PurgeDeletedMembers(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged, "members deleted after the cutoff are kept")
+
+	purged, err = repo.PurgeDeletedMembers(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	_, err = repo.RestoreMember(ctx, bob.ID, 0)
+	assert.ErrorIs(t, err, repositories.ErrNotFound)
+	_, err = repo.GetMemberByID(ctx, alice.ID)
+	assert.NoError(t, err, "members that are not deleted are never purged")
+
+	entries, err := repo.ListAudit(ctx, repositories.AuditFilter{MemberID: bob.ID})
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "the history of purged members is kept")
+}
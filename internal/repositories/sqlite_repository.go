@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// NewSQLiteRepository returns a repository on a SQLite database migrated with the
+// SQLite migrations. Tags are stored as a JSON array in a TEXT column.
+func NewSQLiteRepository(db *sql.DB, opts ...Option) *DBRepository {
+	r := NewDBRepository(db, opts...)
+	r.dialect = sqliteDialect
+	return r
+}
+
+var sqliteDialect = dialect{
+	tags: func(tags *[]string) tagsColumn {
+		return (*jsonTags)(tags)
+	},
+
+	// LIKE ignores the case of ASCII letters in SQLite
+	nameFilter: `name LIKE %s ESCAPE '\'`,
+	anyTags:    "EXISTS (SELECT 1 FROM json_each(tags) AS t WHERE t.value IN (SELECT value FROM json_each(%s)))",
+	allTags:    "NOT EXISTS (SELECT 1 FROM json_each(%s) AS f WHERE f.value NOT IN (SELECT value FROM json_each(tags)))",
+
+	// SQLite has no row locks, writers lock the whole database
+	lockRows: "",
+
+	listTags: `SELECT t.value, count(*) FROM members, json_each(members.tags) AS t
+	WHERE members.` + notDeleted + ` GROUP BY t.value ORDER BY t.value`,
+	mergeTags: `UPDATE members SET tags = (
+		SELECT json_group_array(m.tag) FROM (
+			SELECT CASE WHEN t.value IN (SELECT value FROM json_each($1)) THEN $2 ELSE t.value END AS tag,
+				min(t.key) AS n
+			FROM json_each(members.tags) AS t GROUP BY tag ORDER BY n
+		) m
+	), version = version + 1, ` + resetValidation + `
+	WHERE EXISTS (SELECT 1 FROM json_each(tags) AS t WHERE t.value IN (SELECT value FROM json_each($1))) AND ` + notDeleted,
+	addTag: `UPDATE members SET tags = json_insert(tags, '$[#]', $2), version = version + 1, ` + resetValidation + `
+	WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM json_each(tags) AS t WHERE t.value = $2) RETURNING ` + memberColumns,
+	removeTag: `UPDATE members SET tags = (
+		SELECT json_group_array(t.value) FROM (
+			SELECT value FROM json_each(members.tags) WHERE value <> $2 ORDER BY key
+		) t
+	), version = version + 1, ` + resetValidation + `
+	WHERE id = $1 AND EXISTS (SELECT 1 FROM json_each(tags) AS t WHERE t.value = $2) RETURNING ` + memberColumns,
+
+	payloadMemberID: "json_extract(payload, '$.id')",
+}
+
+// jsonTags stores tags as a JSON array.
+type jsonTags []string
+
+func (t jsonTags) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(t))
+	return string(data), err
+}
+
+func (t *jsonTags) Scan(src interface{}) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		*t = jsonTags{}
+		return nil
+	case string:
+		data = []byte(src)
+	case []byte:
+		data = src
+	default:
+		return fmt.Errorf("cannot scan %T into tags", src)
+	}
+	tags := []string{}
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return err
+	}
+	*t = tags
+	return nil
+}
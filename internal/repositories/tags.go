@@ -0,0 +1,169 @@
+package repositories
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// NormalizeTag folds the tag and checks that it is not empty or too long.
+func NormalizeTag(tag string) (string, error) {
+	tag = models.FoldTag(tag)
+	if tag == "" {
+		return "", &Error{Kind: ErrValidation, Err: fmt.Errorf("tags must not be empty")}
+	}
+	if len(tag) > models.MaxTagLength {
+		return "", &Error{Kind: ErrValidation, Err: fmt.Errorf("tag %q is longer than %d characters", tag, models.MaxTagLength)}
+	}
+	return tag, nil
+}
+
+// NormalizeTags normalizes every tag and drops repeated ones, keeping the first
+// occurrence. It never returns nil so that the result can be stored as is.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+func (r *DBRepository) ListTags(ctx context.Context) ([]models.TagCount, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.listTags)
+	if err != nil {
+		return nil, dbError(ctx, err)
+	}
+	defer rows.Close()
+
+	tags := []models.TagCount{}
+	for rows.Next() {
+		tag := models.TagCount{}
+		if err := rows.Scan(&tag.Tag, &tag.Members); err != nil {
+			return nil, dbError(ctx, err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, err)
+	}
+	return tags, nil
+}
+
+// MergeTags replaces the source tags with the target tag on every member in a
+// single statement, keeping the position of the first replaced tag. Renaming a
+// tag is merging it alone into the new name. It returns the number of members
+// that changed.
+func (r *DBRepository) MergeTags(ctx context.Context, sources []string, target string) (int, error) {
+	sources, err := NormalizeTags(sources)
+	if err != nil {
+		return 0, err
+	}
+	if target, err = NormalizeTag(target); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	count := 0
+	err = r.inTx(ctx, func(tx *sql.Tx) error {
+		// The members as they were before, for the audit trail
+		rows, err := tx.QueryContext(ctx, "SELECT "+memberColumns+" FROM members WHERE "+fmt.Sprintf(r.dialect.anyTags, "$1")+" AND "+notDeleted+r.dialect.lockRows,
+			r.dialect.tags(&sources))
+		if err != nil {
+			return dbError(ctx, err)
+		}
+		previous, err := r.scanMembers(rows)
+		rows.Close()
+		if err != nil {
+			return dbError(ctx, err)
+		}
+		before := map[int]*models.Member{}
+		for _, member := range previous {
+			before[member.ID] = member
+		}
+
+		rows, err = tx.QueryContext(ctx, r.dialect.mergeTags+" RETURNING "+memberColumns, r.dialect.tags(&sources), target)
+		if err != nil {
+			return dbError(ctx, err)
+		}
+		members, err := r.scanMembers(rows)
+		rows.Close()
+		if err != nil {
+			return dbError(ctx, err)
+		}
+		sort.Slice(members, func(i, j int) bool {
+			return members[i].ID < members[j].ID
+		})
+		for _, member := range members {
+			if err := r.writeAudit(ctx, tx, models.AuditUpdated, before[member.ID], member); err != nil {
+				return err
+			}
+			if err := writeEvent(ctx, tx, outbox.MemberUpdated, member); err != nil {
+				return err
+			}
+		}
+		count = len(members)
+		return nil
+	})
+	return count, err
+}
+
+// AddMemberTag adds the tag to the member unless it already has it.
+func (r *DBRepository) AddMemberTag(ctx context.Context, id int, tag string) (*models.Member, error) {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	return r.updateMemberTags(ctx, id, r.dialect.addTag, tag)
+}
+
+// RemoveMemberTag removes the tag from the member if it has it.
+func (r *DBRepository) RemoveMemberTag(ctx context.Context, id int, tag string) (*models.Member, error) {
+	return r.updateMemberTags(ctx, id, r.dialect.removeTag, models.FoldTag(tag))
+}
+
+// updateMemberTags runs a tag update that matches no row when there is nothing
+// to change, in which case the member is returned as is.
+func (r *DBRepository) updateMemberTags(ctx context.Context, id int, query string, tag string) (*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var member *models.Member
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := r.lockMember(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		member, err = r.scanMember(tx.QueryRowContext(ctx, query, id, tag))
+		if err == sql.ErrNoRows {
+			member = before
+			return nil
+		}
+		if err != nil {
+			return dbError(ctx, err)
+		}
+		if err := r.writeAudit(ctx, tx, models.AuditUpdated, before, member); err != nil {
+			return err
+		}
+		return writeEvent(ctx, tx, outbox.MemberUpdated, member)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
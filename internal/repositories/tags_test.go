@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"codelit/internal/models"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{" Go ", "SQL", "go", "Seasoned Leader"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go", "sql", "seasoned leader"}, tags)
+}
+
+func TestNormalizeTagsRejectsInvalidTags(t *testing.T) {
+	_, err := NormalizeTags([]string{"go", "  "})
+	assert.ErrorIs(t, err, ErrValidation)
+
+	_, err = NormalizeTags([]string{strings.Repeat("a", models.MaxTagLength+1)})
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestListTags(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	mock.ExpectQuery("SELECT tag, count\\(\\*\\) FROM members, unnest\\(tags\\) AS tag WHERE deleted_at IS NULL GROUP BY tag ORDER BY tag").
+		WillReturnRows(sqlmock.NewRows([]string{"tag", "count"}).AddRow("go", 3).AddRow("sql", 1))
+
+	// Act
+	tags, err := repo.ListTags(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []models.TagCount{{Tag: "go", Members: 3}, {Tag: "sql", Members: 1}}, tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeTags(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, .*, validated_at, deleted_at FROM members WHERE tags && \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(pq.Array([]string{"golang", "go-lang"})).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(2, "Mary Major", "contractor", "", 6, pq.Array([]string{"sql", "golang"}), 1, "valid", nil, nil, nil).
+			AddRow(1, "John Doe", "employee", "Software Engineer", 0, pq.Array([]string{"golang", "go-lang"}), 2, "pending", nil, nil, nil))
+	mock.ExpectQuery("UPDATE members SET tags = ARRAY\\(.*\\), version = version \\+ 1, validation_status = 'pending', .* WHERE tags && \\$1 AND deleted_at IS NULL RETURNING id, .*, validated_at").
+		WithArgs(pq.Array([]string{"golang", "go-lang"}), "go").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "John Doe", "employee", "Software Engineer", 0, pq.Array([]string{"go"}), 3, "pending", nil, nil, nil).
+			AddRow(2, "Mary Major", "contractor", "", 6, pq.Array([]string{"sql", "go"}), 2, "valid", nil, nil, nil))
+	mock.ExpectExec("INSERT INTO member_audit").
+		WithArgs(1, "updated", "system", nil, 3, `{"tags":{"before":["golang","go-lang"],"after":["go"]}}`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs("member.updated", 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO member_audit").
+		WithArgs(2, "updated", "system", nil, 2, `{"tags":{"before":["sql","golang"],"after":["sql","go"]}}`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs("member.updated", 2, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	// Act
+	count, err := repo.MergeTags(context.Background(), []string{"Golang", "go-lang"}, " Go")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddMemberTag(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, .*, validated_at, deleted_at FROM members WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John Doe", "employee", "Software Engineer", 0, pq.Array([]string{"sql"}), 2, "pending", nil, nil, nil))
+	mock.ExpectQuery("UPDATE members SET tags = array_append\\(tags, \\$2\\), version = version \\+ 1, validation_status = 'pending', .*"+
+		"WHERE id = \\$1 AND NOT tags @> ARRAY\\[\\$2::TEXT\\] RETURNING id, name, type, role, duration, tags, version, validation_status, validation_reason, validated_at").
+		WithArgs(1, "go").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John Doe", "employee", "Software Engineer", 0, pq.Array([]string{"sql", "go"}), 3, "pending", nil, nil, nil))
+	mock.ExpectExec("INSERT INTO member_audit").
+		WithArgs(1, "updated", "system", nil, 3, `{"tags":{"before":["sql"],"after":["sql","go"]}}`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs("member.updated", 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// Act
+	member, err := repo.AddMemberTag(context.Background(), 1, "Go")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sql", "go"}, member.Tags)
+	assert.Equal(t, 3, member.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveMissingMemberTag(t *testing.T) {
+	// Arrange
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+	repo := NewDBRepository(db)
+
+	columns := []string{"id", "name", "type", "role", "duration", "tags", "version", "validation_status", "validation_reason", "validated_at", "deleted_at"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, name, type, role, duration, tags, version, validation_status, validation_reason, validated_at, deleted_at FROM members WHERE id = \\$1 AND deleted_at IS NULL FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectRollback()
+
+	// Act
+	_, err := repo.RemoveMemberTag(context.Background(), 1, "go")
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
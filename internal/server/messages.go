@@ -0,0 +1,96 @@
+package grpcserver
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"codelit/internal/server/pb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func memberMessage(member *models.Member) *pb.Member {
+	message := &pb.Member{
+		Id:               int64(member.ID),
+		Name:             member.Name,
+		Type:             memberTypeMessage(member.Type),
+		Role:             member.Role,
+		Duration:         int32(member.Duration),
+		Tags:             member.Tags,
+		Version:          int64(member.Version),
+		ValidationStatus: member.ValidationStatus,
+		ValidationReason: member.ValidationReason,
+	}
+	if member.ValidatedAt != nil {
+		message.ValidatedAt = timestamppb.New(*member.ValidatedAt)
+	}
+	return message
+}
+
+// memberModel returns the member written by a client. The validation fields are
+// managed by the server and ignored.
+func memberModel(message *pb.Member) *models.Member {
+	return &models.Member{
+		ID:       int(message.GetId()),
+		Name:     message.GetName(),
+		Type:     memberType(message.GetType()),
+		Role:     message.GetRole(),
+		Duration: int(message.GetDuration()),
+		Tags:     message.GetTags(),
+		Version:  int(message.GetVersion()),
+	}
+}
+
+func memberTypeMessage(memberType string) pb.MemberType {
+	switch memberType {
+	case models.MemberTypeContractor:
+		return pb.MemberType_MEMBER_TYPE_CONTRACTOR
+	case models.MemberTypeEmployee:
+		return pb.MemberType_MEMBER_TYPE_EMPLOYEE
+	}
+	return pb.MemberType_MEMBER_TYPE_UNSPECIFIED
+}
+
+// memberType returns the type of the API, empty when unspecified so that the
+// member validation reports it.
+func memberType(memberType pb.MemberType) string {
+	switch memberType {
+	case pb.MemberType_MEMBER_TYPE_CONTRACTOR:
+		return models.MemberTypeContractor
+	case pb.MemberType_MEMBER_TYPE_EMPLOYEE:
+		return models.MemberTypeEmployee
+	}
+	return ""
+}
+
+func eventMessage(event outbox.Event) (*pb.MemberEvent, error) {
+	member, err := event.Member()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.MemberEvent{
+		Id:     event.ID,
+		Type:   event.Type,
+		Member: memberMessage(member),
+		Time:   timestamppb.New(event.CreatedAt),
+	}, nil
+}
+
+// validationStatus is an InvalidArgument status with the violated rules as
+// field violations, the gRPC counterpart of the details of a 422 response.
+func validationStatus(errs models.ValidationErrors) error {
+	details := &errdetails.BadRequest{}
+	for _, err := range errs {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       err.Field,
+			Description: err.Message,
+		})
+	}
+	st, err := status.New(codes.InvalidArgument, "The member is not valid").WithDetails(details)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, errs.Error())
+	}
+	return st.Err()
+}
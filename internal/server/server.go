@@ -0,0 +1,347 @@
+// Package grpcserver serves the members over gRPC for internal services, next
+// to the REST API of package api, with the same repository and validation.
+package grpcserver
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"codelit/internal/repositories"
+	"codelit/internal/server/pb"
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+
+	// watchBatchSize is the number of events read at a time by WatchMembers
+	watchBatchSize = 100
+
+	// maxActorLength is the size of the actor column of the audit trail
+	maxActorLength = 255
+)
+
+// EventSource reads the member events in the order they were written, see
+// outbox.Feed.
+type EventSource interface {
+	Latest(ctx context.Context) (int64, error)
+	After(ctx context.Context, id int64, limit int) ([]outbox.Event, error)
+}
+
+// Server implements the MemberService. It is safe for concurrent use.
+type Server struct {
+	pb.UnimplementedMemberServiceServer
+
+	repo          repositories.MemberRepository
+	events        EventSource
+	watchInterval time.Duration
+	actorKey      string
+
+	closeOnce sync.Once
+	closing   chan struct{}
+}
+
+type Option func(*Server)
+
+// WithEvents lets clients watch the changes of members read from events.
+// Without it WatchMembers is unimplemented.
+func WithEvents(events EventSource) Option {
+	return func(s *Server) {
+		s.events = events
+	}
+}
+
+// WithWatchInterval sets how often watched events are polled.
+func WithWatchInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.watchInterval = interval
+	}
+}
+
+// WithActorMetadata reads the actor of the audit trail from the given metadata
+// key, which a trusted proxy sets once it authenticated the caller, and fails
+// the calls without it with UNAUTHENTICATED. Without it the actor is the one
+// callers claim in x-actor.
+func WithActorMetadata(key string) Option {
+	return func(s *Server) {
+		s.actorKey = strings.ToLower(key)
+	}
+}
+
+func New(repo repositories.MemberRepository, opts ...Option) *Server {
+	s := &Server{
+		repo:          repo,
+		watchInterval: 500 * time.Millisecond,
+		closing:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// GRPCServer returns a gRPC server of the member service, which recovers from
+// panics in the handlers like the REST API does, and audits the changes as made
+// by the actor of the x-actor metadata, or of WithActorMetadata, in the request
+// of x-request-id.
+func (s *Server) GRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ChainUnaryInterceptor(recoverUnary, s.actorUnary), grpc.ChainStreamInterceptor(recoverStream, s.actorStream))
+	server := grpc.NewServer(opts...)
+	pb.RegisterMemberServiceServer(server, s)
+	return server
+}
+
+// Close ends the watch streams, which never end on their own, so that the gRPC
+// server can stop gracefully.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.closing) })
+}
+
+func (s *Server) GetMember(ctx context.Context, req *pb.GetMemberRequest) (*pb.Member, error) {
+	member, err := s.repo.GetMemberByID(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return memberMessage(member), nil
+}
+
+func (s *Server) ListMembers(ctx context.Context, req *pb.ListMembersRequest) (*pb.ListMembersResponse, error) {
+	opts, err := listOptions(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	list, err := s.repo.ListMembers(ctx, opts)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	res := &pb.ListMembersResponse{
+		Members:       make([]*pb.Member, 0, len(list.Members)),
+		NextPageToken: list.NextCursor,
+		TotalSize:     int32(list.Total),
+	}
+	for _, member := range list.Members {
+		res.Members = append(res.Members, memberMessage(member))
+	}
+	return res, nil
+}
+
+// listOptions checks the paging, filtering and ordering of a list request, like
+// the query parameters of GET /members.
+func listOptions(req *pb.ListMembersRequest) (repositories.ListOptions, error) {
+	opts := repositories.ListOptions{
+		Limit:    int(req.GetPageSize()),
+		Type:     memberType(req.GetType()),
+		Role:     req.GetRole(),
+		Name:     strings.TrimSpace(req.GetName()),
+		Status:   req.GetValidationStatus(),
+		Tags:     req.GetTags(),
+		TagMatch: repositories.TagMatchAny,
+		Cursor:   req.GetPageToken(),
+	}
+	if opts.Limit == 0 {
+		opts.Limit = defaultPageSize
+	}
+	if opts.Limit < 1 || opts.Limit > maxPageSize {
+		return opts, errors.New("page_size must be between 1 and 500")
+	}
+	if opts.Status != "" && !models.IsValidationStatus(opts.Status) {
+		return opts, errors.New("validation_status must be 'pending', 'valid', 'rejected' or 'error'")
+	}
+	if req.GetAllTags() {
+		opts.TagMatch = repositories.TagMatchAll
+	}
+	var err error
+	if opts.Sort, err = repositories.ParseSort(req.GetOrderBy()); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+func (s *Server) CreateMember(ctx context.Context, req *pb.CreateMemberRequest) (*pb.Member, error) {
+	member := memberModel(req.GetMember())
+	member.ID, member.Version = 0, 0
+	if err := member.Validate(); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := s.repo.CreateMember(ctx, member); err != nil {
+		return nil, toStatus(err)
+	}
+	return memberMessage(member), nil
+}
+
+func (s *Server) UpdateMember(ctx context.Context, req *pb.UpdateMemberRequest) (*pb.Member, error) {
+	member := memberModel(req.GetMember())
+	if err := member.Validate(); err != nil {
+		return nil, toStatus(err)
+	}
+
+	if err := s.repo.UpdateMember(ctx, member); err != nil {
+		return nil, toStatus(err)
+	}
+	return memberMessage(member), nil
+}
+
+func (s *Server) DeleteMember(ctx context.Context, req *pb.DeleteMemberRequest) (*pb.DeleteMemberResponse, error) {
+	if err := s.repo.DeleteMember(ctx, int(req.GetId()), int(req.GetVersion())); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.DeleteMemberResponse{}, nil
+}
+
+// WatchMembers polls the events following the requested one and sends them in
+// order until the client goes away or the server closes.
+func (s *Server) WatchMembers(req *pb.WatchMembersRequest, stream pb.MemberService_WatchMembersServer) error {
+	if s.events == nil {
+		return status.Error(codes.Unimplemented, "watching members needs a database")
+	}
+	ctx := stream.Context()
+
+	after := req.GetAfterEventId()
+	if req.AfterEventId == nil {
+		var err error
+		if after, err = s.events.Latest(ctx); err != nil {
+			return toStatus(err)
+		}
+	}
+
+	for {
+		events, err := s.events.After(ctx, after, watchBatchSize)
+		if err != nil {
+			return toStatus(err)
+		}
+		for _, event := range events {
+			message, err := eventMessage(event)
+			if err != nil {
+				return toStatus(err)
+			}
+			if err := stream.Send(message); err != nil {
+				return err
+			}
+			after = event.ID
+		}
+		if len(events) == watchBatchSize {
+			continue
+		}
+
+		timer := time.NewTimer(s.watchInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return status.FromContextError(ctx.Err()).Err()
+		case <-s.closing:
+			timer.Stop()
+			return status.Error(codes.Unavailable, "the server is shutting down, resume watching after the last event")
+		case <-timer.C:
+		}
+	}
+}
+
+// toStatus turns repository and validation errors into gRPC statuses with the
+// same meaning as the HTTP statuses of the REST API.
+func toStatus(err error) error {
+	var validationErrs models.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		return validationStatus(validationErrs)
+	case errors.Is(err, repositories.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, repositories.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repositories.ErrVersionMismatch):
+		return status.Error(codes.Aborted, "the member changed, get it again before retrying")
+	case errors.Is(err, repositories.ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, repositories.ErrValidation):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, repositories.ErrUnavailable):
+		return status.Error(codes.Unavailable, "the database is unavailable")
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return status.FromContextError(err).Err()
+	default:
+		log.Printf("Internal error in the member service: %v", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func recoverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+	defer recoverPanic(info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+// actorUnary sets the actor of the request in its context, for the audit trail.
+// Services that do not tell who they are act as anonymous, like REST clients,
+// unless the actor must be set by a trusted proxy.
+func (s *Server) actorUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	actor, err := s.actor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(repositories.ContextWithActor(ctx, actor), req)
+}
+
+// actorStream is actorUnary for streams, so that the streams also require the
+// actor set by a trusted proxy.
+func (s *Server) actorStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	actor, err := s.actor(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &actorServerStream{ServerStream: stream, ctx: repositories.ContextWithActor(stream.Context(), actor)})
+}
+
+// actor returns the actor of the metadata of the request.
+func (s *Server) actor(ctx context.Context) (models.Actor, error) {
+	key := "x-actor"
+	if s.actorKey != "" {
+		key = s.actorKey
+	}
+	actor := models.Actor{Name: "anonymous"}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(key); len(values) > 0 && values[0] != "" {
+		actor.Name = values[0]
+	} else if s.actorKey != "" {
+		return actor, status.Errorf(codes.Unauthenticated, "%s is required", key)
+	}
+	if values := md.Get("x-request-id"); len(values) > 0 {
+		actor.RequestID = values[0]
+	}
+	if len(actor.Name) > maxActorLength {
+		return actor, status.Errorf(codes.InvalidArgument, "%s must be at most %d characters", key, maxActorLength)
+	}
+	return actor, nil
+}
+
+// actorServerStream is a stream whose context holds the actor.
+type actorServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *actorServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func recoverStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverPanic(info.FullMethod, &err)
+	return handler(srv, stream)
+}
+
+func recoverPanic(method string, err *error) {
+	if r := recover(); r != nil {
+		log.Printf("Panic in %s: %v\n%s", method, r, debug.Stack())
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
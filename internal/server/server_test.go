@@ -0,0 +1,358 @@
+package grpcserver
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"codelit/internal/repositories"
+	"codelit/internal/server/pb"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeEvents is an EventSource of the events added by the test. It closes
+// watching, when set, once a watch started from the latest event.
+type fakeEvents struct {
+	mu       sync.Mutex
+	events   []outbox.Event
+	watching chan struct{}
+}
+
+func (f *fakeEvents) add(t *testing.T, eventType string, member *models.Member) {
+	payload, err := json.Marshal(member)
+	require.NoError(t, err)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, outbox.Event{ID: int64(len(f.events) + 1), Type: eventType, MemberID: member.ID, Payload: payload, CreatedAt: time.Now()})
+}
+
+func (f *fakeEvents) Latest(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.watching != nil {
+		close(f.watching)
+		f.watching = nil
+	}
+	return int64(len(f.events)), nil
+}
+
+func (f *fakeEvents) After(ctx context.Context, id int64, limit int) ([]outbox.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := []outbox.Event{}
+	for _, event := range f.events {
+		if event.ID > id && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// newClient serves the member service in memory and returns a client of it.
+func newClient(t *testing.T, server *Server) pb.MemberServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := server.GRPCServer()
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewMemberServiceClient(conn)
+}
+
+var alice = &pb.Member{Name: "Alice", Type: pb.MemberType_MEMBER_TYPE_EMPLOYEE, Role: "Engineer", Tags: []string{"Go"}}
+
+func TestCreateAndGetMember(t *testing.T) {
+	// Arrange
+	client := newClient(t, New(repositories.NewMemoryRepository()))
+
+	// Act
+	created, createErr := client.CreateMember(context.Background(), &pb.CreateMemberRequest{Member: alice})
+	require.NoError(t, createErr)
+	got, getErr := client.GetMember(context.Background(), &pb.GetMemberRequest{Id: created.GetId()})
+
+	// Assert
+	require.NoError(t, getErr)
+	assert.Equal(t, int64(1), created.GetId())
+	assert.Equal(t, int64(1), created.GetVersion())
+	assert.Equal(t, models.ValidationPending, created.GetValidationStatus())
+	assert.Equal(t, []string{"go"}, got.GetTags())
+	assert.True(t, proto.Equal(created, got))
+}
+
+func TestCreateInvalidMember(t *testing.T) {
+	// Arrange
+	client := newClient(t, New(repositories.NewMemoryRepository()))
+
+	// Act
+	_, err := client.CreateMember(context.Background(), &pb.CreateMemberRequest{
+		Member: &pb.Member{Type: pb.MemberType_MEMBER_TYPE_CONTRACTOR, Role: "Engineer", Duration: 6},
+	})
+
+	// Assert
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	if assert.Len(t, st.Details(), 1) {
+		violations := st.Details()[0].(*errdetails.BadRequest).GetFieldViolations()
+		if assert.Len(t, violations, 2) {
+			assert.Equal(t, "name", violations[0].GetField())
+			assert.Equal(t, "Contractors must not have a role", violations[1].GetDescription())
+		}
+	}
+}
+
+func TestGetMissingMember(t *testing.T) {
+	// Arrange
+	client := newClient(t, New(repositories.NewMemoryRepository()))
+
+	// Act
+	_, err := client.GetMember(context.Background(), &pb.GetMemberRequest{Id: 42})
+
+	// Assert
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestUpdateMember(t *testing.T) {
+	// Arrange
+	client := newClient(t, New(repositories.NewMemoryRepository()))
+	created, err := client.CreateMember(context.Background(), &pb.CreateMemberRequest{Member: alice})
+	require.NoError(t, err)
+	update := proto.Clone(created).(*pb.Member)
+	update.Role = "Tech Lead"
+
+	// Act
+	updated, updateErr := client.UpdateMember(context.Background(), &pb.UpdateMemberRequest{Member: update})
+	_, staleErr := client.UpdateMember(context.Background(), &pb.UpdateMemberRequest{Member: update})
+
+	// Assert
+	require.NoError(t, updateErr)
+	assert.Equal(t, "Tech Lead", updated.GetRole())
+	assert.Equal(t, int64(2), updated.GetVersion())
+	assert.Equal(t, codes.Aborted, status.Code(staleErr))
+}
+
+func TestChangesAreAuditedWithActor(t *testing.T) {
+	// Arrange
+	repo := repositories.NewMemoryRepository()
+	client := newClient(t, New(repo))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-actor", "billing-service", "x-request-id", "req-1")
+
+	// Act
+	created, createErr := client.CreateMember(ctx, &pb.CreateMemberRequest{Member: alice})
+	_, anonymousErr := client.DeleteMember(context.Background(), &pb.DeleteMemberRequest{Id: created.GetId()})
+
+	// Assert
+	require.NoError(t, createErr)
+	require.NoError(t, anonymousErr)
+	entries, err := repo.ListAudit(context.Background(), repositories.AuditFilter{MemberID: int(created.GetId())})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "anonymous", entries[0].Actor)
+	assert.Empty(t, entries[0].RequestID)
+	assert.Equal(t, "billing-service", entries[1].Actor)
+	assert.Equal(t, "req-1", entries[1].RequestID)
+}
+
+func TestTrustedActorMetadata(t *testing.T) {
+	// Arrange
+	repo := repositories.NewMemoryRepository()
+	client := newClient(t, New(repo, WithActorMetadata("X-Forwarded-User")))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-forwarded-user", "billing-service", "x-actor", "mallory")
+
+	// Act
+	created, createErr := client.CreateMember(ctx, &pb.CreateMemberRequest{Member: alice})
+	_, missingErr := client.DeleteMember(metadata.AppendToOutgoingContext(context.Background(), "x-actor", "mallory"),
+		&pb.DeleteMemberRequest{Id: created.GetId()})
+
+	// Assert
+	require.NoError(t, createErr)
+	assert.Equal(t, codes.Unauthenticated, status.Code(missingErr))
+	entries, err := repo.ListAudit(context.Background(), repositories.AuditFilter{MemberID: int(created.GetId())})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "billing-service", entries[0].Actor)
+}
+
+func TestTrustedActorMetadataOnStreams(t *testing.T) {
+	// Arrange
+	events := &fakeEvents{}
+	events.add(t, outbox.MemberCreated, &models.Member{ID: 1, Name: "Alice", Version: 1})
+	client := newClient(t, New(repositories.NewMemoryRepository(), WithEvents(events), WithActorMetadata("X-Forwarded-User")))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req := &pb.WatchMembersRequest{AfterEventId: proto.Int64(0)}
+
+	// Act
+	missing, err := client.WatchMembers(metadata.AppendToOutgoingContext(ctx, "x-actor", "mallory"), req)
+	require.NoError(t, err)
+	_, missingErr := missing.Recv()
+	trusted, err := client.WatchMembers(metadata.AppendToOutgoingContext(ctx, "x-forwarded-user", "billing-service"), req)
+	require.NoError(t, err)
+	first, trustedErr := trusted.Recv()
+
+	// Assert
+	assert.Equal(t, codes.Unauthenticated, status.Code(missingErr))
+	require.NoError(t, trustedErr)
+	assert.Equal(t, int64(1), first.GetId())
+}
+
+func TestDeleteMember(t *testing.T) {
+	// Arrange
+	client := newClient(t, New(repositories.NewMemoryRepository()))
+	created, err := client.CreateMember(context.Background(), &pb.CreateMemberRequest{Member: alice})
+	require.NoError(t, err)
+
+	// Act
+	_, deleteErr := client.DeleteMember(context.Background(), &pb.DeleteMemberRequest{Id: created.GetId(), Version: created.GetVersion()})
+	_, againErr := client.DeleteMember(context.Background(), &pb.DeleteMemberRequest{Id: created.GetId()})
+
+	// Assert
+	assert.NoError(t, deleteErr)
+	assert.Equal(t, codes.NotFound, status.Code(againErr))
+}
+
+func TestListMembers(t *testing.T) {
+	// Arrange
+	client := newClient(t, New(repositories.NewMemoryRepository()))
+	for _, name := range []string{"Carol", "Alice", "Bob"} {
+		_, err := client.CreateMember(context.Background(), &pb.CreateMemberRequest{
+			Member: &pb.Member{Name: name, Type: pb.MemberType_MEMBER_TYPE_CONTRACTOR, Duration: 6},
+		})
+		require.NoError(t, err)
+	}
+
+	// Act
+	first, firstErr := client.ListMembers(context.Background(), &pb.ListMembersRequest{PageSize: 2, OrderBy: "name"})
+	require.NoError(t, firstErr)
+	second, secondErr := client.ListMembers(context.Background(), &pb.ListMembersRequest{PageSize: 2, OrderBy: "name", PageToken: first.GetNextPageToken()})
+
+	// Assert
+	require.NoError(t, secondErr)
+	names := []string{}
+	for _, member := range append(first.GetMembers(), second.GetMembers()...) {
+		names = append(names, member.GetName())
+	}
+	assert.Equal(t, []string{"Alice", "Bob", "Carol"}, names)
+	assert.Equal(t, int32(3), first.GetTotalSize())
+	assert.Empty(t, second.GetNextPageToken())
+}
+
+func TestListMembersRejectsInvalidOptions(t *testing.T) {
+	client := newClient(t, New(repositories.NewMemoryRepository()))
+	tests := []*pb.ListMembersRequest{
+		{PageSize: 501},
+		{PageSize: -1},
+		{ValidationStatus: "unknown"},
+		{OrderBy: "salary"},
+		{PageToken: "not a cursor"},
+	}
+
+	for _, test := range tests {
+		// Act
+		_, err := client.ListMembers(context.Background(), test)
+
+		// Assert
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), test.String())
+	}
+}
+
+func TestWatchMembers(t *testing.T) {
+	// Arrange
+	events := &fakeEvents{}
+	events.add(t, outbox.MemberCreated, &models.Member{ID: 1, Name: "Alice", Version: 1})
+	server := New(repositories.NewMemoryRepository(), WithEvents(events), WithWatchInterval(time.Millisecond))
+	client := newClient(t, server)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Act
+	replay, err := client.WatchMembers(ctx, &pb.WatchMembersRequest{AfterEventId: proto.Int64(0)})
+	require.NoError(t, err)
+	first, firstErr := replay.Recv()
+	events.add(t, outbox.MemberUpdated, &models.Member{ID: 1, Name: "Alice Smith", Version: 2})
+	second, secondErr := replay.Recv()
+	server.Close()
+	_, closedErr := replay.Recv()
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, secondErr)
+	assert.Equal(t, int64(1), first.GetId())
+	assert.Equal(t, outbox.MemberCreated, first.GetType())
+	assert.Equal(t, "Alice", first.GetMember().GetName())
+	assert.Equal(t, int64(2), second.GetId())
+	assert.Equal(t, "Alice Smith", second.GetMember().GetName())
+	assert.Equal(t, codes.Unavailable, status.Code(closedErr))
+}
+
+func TestWatchMembersFromNow(t *testing.T) {
+	// Arrange
+	watching := make(chan struct{})
+	events := &fakeEvents{watching: watching}
+	events.add(t, outbox.MemberCreated, &models.Member{ID: 1, Name: "Alice", Version: 1})
+	client := newClient(t, New(repositories.NewMemoryRepository(), WithEvents(events), WithWatchInterval(time.Millisecond)))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Act
+	stream, err := client.WatchMembers(ctx, &pb.WatchMembersRequest{})
+	require.NoError(t, err)
+	<-watching
+	events.add(t, outbox.MemberDeleted, &models.Member{ID: 1, Name: "Alice", Version: 1})
+	event, err := stream.Recv()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), event.GetId())
+	assert.Equal(t, outbox.MemberDeleted, event.GetType())
+}
+
+func TestWatchMembersWithoutEvents(t *testing.T) {
+	// Arrange
+	client := newClient(t, New(repositories.NewMemoryRepository()))
+
+	// Act
+	stream, err := client.WatchMembers(context.Background(), &pb.WatchMembersRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+
+	// Assert
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{&repositories.Error{Kind: repositories.ErrConflict, Err: errors.New("duplicate")}, codes.AlreadyExists},
+		{&repositories.Error{Kind: repositories.ErrValidation, Err: errors.New("too long")}, codes.InvalidArgument},
+		{&repositories.Error{Kind: repositories.ErrUnavailable, Err: errors.New("connection reset")}, codes.Unavailable},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+		{errors.New("boom"), codes.Internal},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.code, status.Code(toStatus(test.err)), test.err.Error())
+	}
+}
@@ -0,0 +1,272 @@
+// Package validation validates members on a pool of workers and records the
+// verdicts. Members are validated by the remote validation service, by local
+// rules, or by a combination of both.
+package validation
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"codelit/internal/repositories"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Recorder stores verdicts, see repositories.MemberRepository.
+type Recorder interface {
+	RecordValidation(ctx context.Context, id int, version int, validation models.Validation) error
+}
+
+var (
+	// ErrQueueFull is returned when a member cannot wait for a worker.
+	ErrQueueFull = errors.New("the validation queue is full")
+	// ErrShutdown is returned for the members that were not validated because
+	// the dispatcher shut down.
+	ErrShutdown = errors.New("the validation dispatcher is shut down")
+)
+
+// Dispatcher validates the queued members on a fixed number of workers. The
+// queue is bounded: when it is full, members are refused instead of piling up
+// or blocking their callers for long. It is safe for concurrent use.
+type Dispatcher struct {
+	validator Validator
+	recorder  Recorder
+	workers   int
+	queue     chan job
+
+	mu      sync.RWMutex
+	started bool
+	closed  bool
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	inFlight  atomic.Int64
+	enqueued  atomic.Int64
+	dropped   atomic.Int64
+	completed atomic.Int64
+	failed    atomic.Int64
+	stale     atomic.Int64
+}
+
+// Stats tells how busy the dispatcher is. Counters are totals since it started.
+type Stats struct {
+	Workers       int   `json:"workers"`
+	QueueCapacity int   `json:"queue_capacity"`
+	Queued        int   `json:"queued"`
+	InFlight      int64 `json:"in_flight"`
+	Enqueued      int64 `json:"enqueued"`
+	Dropped       int64 `json:"dropped"`   // refused because the queue was full or shut down
+	Completed     int64 `json:"completed"` // verdict recorded
+	Failed        int64 `json:"failed"`    // verdict could not be recorded
+	Stale         int64 `json:"stale"`     // member changed while it was validated
+}
+
+// job is a member to validate, copied so that the caller may change its own
+// copy, and where to send the outcome.
+type job struct {
+	member models.Member
+	done   chan error
+}
+
+type Option func(*Dispatcher)
+
+// WithWorkers sets how many members are validated at the same time.
+func WithWorkers(workers int) Option {
+	return func(d *Dispatcher) {
+		d.workers = workers
+	}
+}
+
+// WithQueueSize sets how many members can wait for a worker.
+func WithQueueSize(size int) Option {
+	return func(d *Dispatcher) {
+		d.queue = make(chan job, size)
+	}
+}
+
+// NewDispatcher returns a dispatcher asking validator for verdicts. Members can
+// be queued right away, they are validated once Start is called.
+func NewDispatcher(validator Validator, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		validator: validator,
+		workers:   4,
+		queue:     make(chan job, 100),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.workers < 1 {
+		d.workers = 1
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	return d
+}
+
+// Start starts the workers, which store the verdicts with recorder, usually the
+// member repository. It does nothing when the workers already run.
+func (d *Dispatcher) Start(recorder Recorder) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.started || d.closed {
+		return
+	}
+	d.started = true
+	d.recorder = recorder
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+}
+
+// Validate queues a copy of the member and waits until its verdict is recorded.
+// It fails right away with ErrQueueFull when the queue is full. A verdict that
+// came too late because the member changed in the meantime is not an error.
+func (d *Dispatcher) Validate(ctx context.Context, member *models.Member) error {
+	j := job{member: *member, done: make(chan error, 1)}
+	j.member.Tags = append([]string(nil), member.Tags...)
+	if member.ValidatedAt != nil {
+		at := *member.ValidatedAt
+		j.member.ValidatedAt = &at
+	}
+
+	if err := d.enqueue(j); err != nil {
+		d.dropped.Add(1)
+		return err
+	}
+	d.enqueued.Add(1)
+
+	select {
+	case err := <-j.done:
+		return err
+	case <-ctx.Done():
+		// The member is still validated, its verdict is just not awaited
+		return ctx.Err()
+	}
+}
+
+// HandleEvent validates the member of an outbox event, so that the dispatcher
+// can be registered as the handler of the events calling for a validation.
+func (d *Dispatcher) HandleEvent(ctx context.Context, event outbox.Event) error {
+	member, err := event.Member()
+	if err != nil {
+		return err
+	}
+	return d.Validate(ctx, member)
+}
+
+func (d *Dispatcher) enqueue(j job) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return ErrShutdown
+	}
+	select {
+	case d.queue <- j:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Shutdown stops accepting members and waits until the queued ones are
+// validated. When ctx is done first, the validations still running are
+// cancelled and the members left in the queue fail with ErrShutdown.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	started := d.started
+	d.mu.Unlock()
+
+	if !started {
+		d.cancel()
+		for j := range d.queue {
+			j.done <- ErrShutdown
+		}
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Stats returns the current state of the queue and the counters.
+func (d *Dispatcher) Stats() Stats {
+	return Stats{
+		Workers:       d.workers,
+		QueueCapacity: cap(d.queue),
+		Queued:        len(d.queue),
+		InFlight:      d.inFlight.Load(),
+		Enqueued:      d.enqueued.Load(),
+		Dropped:       d.dropped.Load(),
+		Completed:     d.completed.Load(),
+		Failed:        d.failed.Load(),
+		Stale:         d.stale.Load(),
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for j := range d.queue {
+		if d.ctx.Err() != nil {
+			// Shutting down in a hurry
+			j.done <- ErrShutdown
+			continue
+		}
+		d.inFlight.Add(1)
+		j.done <- d.validate(&j.member)
+		d.inFlight.Add(-1)
+	}
+}
+
+// validate asks for a verdict on the member and records it. When the validator
+// cannot be asked, the member is in error.
+func (d *Dispatcher) validate(member *models.Member) error {
+	verdict, err := d.validator.ValidateMember(d.ctx, member)
+	validation := models.Validation{Status: models.ValidationValid, At: time.Now()}
+	switch {
+	case err != nil && d.ctx.Err() != nil:
+		// Shutting down in a hurry, the member stays pending
+		return ErrShutdown
+	case err != nil:
+		log.Printf("Could not validate member %d: %v", member.ID, err)
+		validation.Status, validation.Reason = models.ValidationError, err.Error()
+	case !verdict.Valid:
+		log.Printf("Member %d was rejected: %v", member.ID, verdict.Reasons)
+		validation.Status, validation.Reason = models.ValidationRejected, verdict.Reasons.Error()
+	}
+
+	err = d.recorder.RecordValidation(d.ctx, member.ID, member.Version, validation)
+	switch {
+	case errors.Is(err, repositories.ErrVersionMismatch), errors.Is(err, repositories.ErrNotFound):
+		// The member changed or was deleted while it was validated, the verdict is stale
+		d.stale.Add(1)
+		return nil
+	case err != nil:
+		log.Printf("Could not record the validation of member %d: %v", member.ID, err)
+		d.failed.Add(1)
+		return err
+	}
+	d.completed.Add(1)
+	return nil
+}
@@ -0,0 +1,213 @@
+package validation
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"codelit/internal/repositories"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validatorFunc is a Validator answering with a function.
+type validatorFunc func(ctx context.Context, member *models.Member) (*models.Verdict, error)
+
+func (f validatorFunc) ValidateMember(ctx context.Context, member *models.Member) (*models.Verdict, error) {
+	return f(ctx, member)
+}
+
+// rejectContractors accepts employees only.
+var rejectContractors = validatorFunc(func(ctx context.Context, member *models.Member) (*models.Verdict, error) {
+	if member.Type == models.MemberTypeContractor {
+		return &models.Verdict{Reasons: models.ValidationErrors{{Field: "type", Message: "contractors are not allowed"}}}, nil
+	}
+	return &models.Verdict{Valid: true}, nil
+})
+
+func newRepository(t *testing.T, members ...*models.Member) *repositories.MemoryRepository {
+	repo := repositories.NewMemoryRepository()
+	for _, member := range members {
+		require.NoError(t, repo.CreateMember(context.Background(), member))
+	}
+	return repo
+}
+
+// startDispatcher returns a started dispatcher, shut down when the test ends.
+func startDispatcher(t *testing.T, validator Validator, recorder Recorder, opts ...Option) *Dispatcher {
+	d := NewDispatcher(validator, opts...)
+	d.Start(recorder)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		d.Shutdown(ctx)
+	})
+	return d
+}
+
+func storedMember(t *testing.T, repo *repositories.MemoryRepository, id int) *models.Member {
+	t.Helper()
+	member, err := repo.GetMemberByID(context.Background(), id)
+	require.NoError(t, err)
+	return member
+}
+
+func TestDispatcherRecordsVerdicts(t *testing.T) {
+	// Arrange
+	alice := &models.Member{Name: "Alice", Type: models.MemberTypeEmployee, Role: "Engineer"}
+	bob := &models.Member{Name: "Bob", Type: models.MemberTypeContractor, Duration: 6}
+	repo := newRepository(t, alice, bob)
+	d := startDispatcher(t, rejectContractors, repo, WithWorkers(2))
+
+	// Act
+	errAlice := d.Validate(context.Background(), alice)
+	errBob := d.Validate(context.Background(), bob)
+
+	// Assert
+	assert.NoError(t, errAlice)
+	assert.NoError(t, errBob)
+	assert.Equal(t, models.ValidationValid, storedMember(t, repo, alice.ID).ValidationStatus)
+	rejected := storedMember(t, repo, bob.ID)
+	assert.Equal(t, models.ValidationRejected, rejected.ValidationStatus)
+	assert.Equal(t, "contractors are not allowed", rejected.ValidationReason)
+	assert.NotNil(t, rejected.ValidatedAt)
+	stats := d.Stats()
+	assert.Equal(t, int64(2), stats.Enqueued)
+	assert.Equal(t, int64(2), stats.Completed)
+	assert.Zero(t, stats.Queued)
+}
+
+func TestDispatcherRecordsErrors(t *testing.T) {
+	// Arrange
+	alice := &models.Member{Name: "Alice", Type: models.MemberTypeEmployee, Role: "Engineer"}
+	repo := newRepository(t, alice)
+	unavailable := validatorFunc(func(ctx context.Context, member *models.Member) (*models.Verdict, error) {
+		return nil, errors.New("service unavailable")
+	})
+	d := startDispatcher(t, unavailable, repo)
+
+	// Act
+	err := d.Validate(context.Background(), alice)
+
+	// Assert
+	assert.NoError(t, err)
+	stored := storedMember(t, repo, alice.ID)
+	assert.Equal(t, models.ValidationError, stored.ValidationStatus)
+	assert.Equal(t, "service unavailable", stored.ValidationReason)
+}
+
+func TestDispatcherHandlesEvents(t *testing.T) {
+	// Arrange
+	bob := &models.Member{Name: "Bob", Type: models.MemberTypeContractor, Duration: 6}
+	repo := newRepository(t, bob)
+	d := startDispatcher(t, rejectContractors, repo)
+	payload, err := json.Marshal(bob)
+	require.NoError(t, err)
+
+	// Act
+	err = d.HandleEvent(context.Background(), outbox.Event{Type: outbox.MemberCreated, MemberID: bob.ID, Payload: payload})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.ValidationRejected, storedMember(t, repo, bob.ID).ValidationStatus)
+}
+
+func TestDispatcherCopiesMembers(t *testing.T) {
+	// Arrange
+	alice := &models.Member{Name: "Alice", Type: models.MemberTypeEmployee, Role: "Engineer", Tags: []string{"go"}}
+	repo := newRepository(t, alice)
+	called, release := make(chan struct{}), make(chan struct{})
+	var seen models.Member
+	record := validatorFunc(func(ctx context.Context, member *models.Member) (*models.Verdict, error) {
+		close(called)
+		<-release
+		seen = *member
+		return &models.Verdict{Valid: true}, nil
+	})
+	d := startDispatcher(t, record, repo)
+	member := *alice
+	member.Tags = []string{"go"}
+
+	// Act
+	done := make(chan error)
+	go func() { done <- d.Validate(context.Background(), &member) }()
+	<-called
+	member.Name = "Changed"
+	member.Tags[0] = "changed"
+	close(release)
+
+	// Assert
+	assert.NoError(t, <-done)
+	assert.Equal(t, "Alice", seen.Name)
+	assert.Equal(t, []string{"go"}, seen.Tags)
+}
+
+func TestDispatcherRefusesWhenFull(t *testing.T) {
+	// Arrange
+	d := NewDispatcher(rejectContractors, WithQueueSize(1))
+	member := &models.Member{ID: 1, Name: "Alice", Type: models.MemberTypeEmployee, Role: "Engineer", Version: 1}
+	waiting := make(chan error)
+	go func() { waiting <- d.Validate(context.Background(), member) }()
+	require.Eventually(t, func() bool { return d.Stats().Queued == 1 }, time.Second, time.Millisecond)
+
+	// Act
+	err := d.Validate(context.Background(), member)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrQueueFull)
+	stats := d.Stats()
+	assert.Equal(t, 1, stats.QueueCapacity)
+	assert.Equal(t, int64(1), stats.Dropped)
+	require.NoError(t, d.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-waiting, ErrShutdown)
+}
+
+func TestDispatcherDiscardsStaleVerdicts(t *testing.T) {
+	// Arrange
+	bob := &models.Member{Name: "Bob", Type: models.MemberTypeContractor, Duration: 6}
+	repo := newRepository(t, bob)
+	d := startDispatcher(t, rejectContractors, repo)
+
+	update := &models.Member{ID: bob.ID, Name: "Bob", Type: models.MemberTypeEmployee, Role: "Engineer"}
+	require.NoError(t, repo.UpdateMember(context.Background(), update))
+
+	// Act
+	err := d.Validate(context.Background(), bob)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, models.ValidationPending, storedMember(t, repo, bob.ID).ValidationStatus)
+	assert.Equal(t, int64(1), d.Stats().Stale)
+}
+
+func TestDispatcherShutdownGivesUp(t *testing.T) {
+	// Arrange
+	alice := &models.Member{Name: "Alice", Type: models.MemberTypeEmployee, Role: "Engineer"}
+	repo := newRepository(t, alice)
+	started := make(chan struct{})
+	hang := validatorFunc(func(ctx context.Context, member *models.Member) (*models.Verdict, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	d := startDispatcher(t, hang, repo, WithWorkers(1))
+	waiting := make(chan error)
+	go func() { waiting <- d.Validate(context.Background(), alice) }()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Act
+	err := d.Shutdown(ctx)
+
+	// Assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, <-waiting, ErrShutdown)
+	assert.Equal(t, models.ValidationPending, storedMember(t, repo, alice.ID).ValidationStatus)
+	assert.ErrorIs(t, d.Validate(context.Background(), alice), ErrShutdown)
+}
@@ -0,0 +1,124 @@
+package validation
+
+import (
+	"bytes"
+	"codelit/internal/models"
+	"codelit/internal/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rules is a local validator checking members against declarative rules, usually
+// loaded from a YAML file:
+//
+//	roles: [Software Engineer, Tech Lead]
+//	max_contractor_duration: 12
+//	required_tags:
+//	  contractor: [external]
+//
+// The zero value accepts every member.
+type Rules struct {
+	// Roles lists the allowed roles, compared regardless of case. Any role is
+	// allowed when empty.
+	Roles []string `yaml:"roles"`
+	// MaxContractorDuration is the longest contract, unlimited when zero.
+	MaxContractorDuration int `yaml:"max_contractor_duration"`
+	// RequiredTags lists the tags members must have by member type.
+	RequiredTags map[string][]string `yaml:"required_tags"`
+}
+
+// LoadRules reads the rules in the YAML file at path.
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the validation rules: %w", err)
+	}
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validation rules in %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// ParseRules decodes YAML rules. Unknown keys are refused so that a misspelled
+// rule is not silently ignored.
+func ParseRules(data []byte) (*Rules, error) {
+	rules := &Rules{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if rules.MaxContractorDuration < 0 {
+		return nil, fmt.Errorf("max_contractor_duration must not be negative")
+	}
+	for memberType, tags := range rules.RequiredTags {
+		if memberType != models.MemberTypeContractor && memberType != models.MemberTypeEmployee {
+			return nil, fmt.Errorf("required_tags: unknown member type %q", memberType)
+		}
+		normalized, err := repositories.NormalizeTags(tags)
+		if err != nil {
+			return nil, fmt.Errorf("required_tags: %w", err)
+		}
+		rules.RequiredTags[memberType] = normalized
+	}
+	return rules, nil
+}
+
+// ValidateMember checks the member against every rule and rejects it with all
+// the violations at once. It never fails.
+func (r *Rules) ValidateMember(ctx context.Context, member *models.Member) (*models.Verdict, error) {
+	reasons := models.ValidationErrors{}
+
+	if member.Role != "" && len(r.Roles) > 0 && !r.allowsRole(member.Role) {
+		reasons = append(reasons, models.FieldError{
+			Field:   "role",
+			Code:    models.CodeForbidden,
+			Message: fmt.Sprintf("Role %q is not allowed", member.Role),
+		})
+	}
+	if member.Type == models.MemberTypeContractor && r.MaxContractorDuration > 0 && member.Duration > r.MaxContractorDuration {
+		reasons = append(reasons, models.FieldError{
+			Field:   "duration",
+			Code:    models.CodeInvalid,
+			Message: fmt.Sprintf("Contracts must not last more than %d", r.MaxContractorDuration),
+		})
+	}
+	for _, tag := range r.RequiredTags[member.Type] {
+		if !hasTag(member.Tags, tag) {
+			reasons = append(reasons, models.FieldError{
+				Field:   "tags",
+				Code:    models.CodeRequired,
+				Message: fmt.Sprintf("Tag %q is required for %ss", tag, member.Type),
+			})
+		}
+	}
+
+	return &models.Verdict{Valid: len(reasons) == 0, Reasons: reasons}, nil
+}
+
+func (r *Rules) allowsRole(role string) bool {
+	role = strings.TrimSpace(role)
+	for _, allowed := range r.Roles {
+		if strings.EqualFold(role, strings.TrimSpace(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(strings.TrimSpace(t), tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package validation
+
+import (
+	"codelit/internal/models"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rulesYAML = `
+roles: [Software Engineer, Tech Lead]
+max_contractor_duration: 12
+required_tags:
+  contractor: [External]
+`
+
+func TestRulesAcceptMembers(t *testing.T) {
+	// Arrange
+	rules, err := ParseRules([]byte(rulesYAML))
+	require.NoError(t, err)
+	members := []*models.Member{
+		{Name: "Alice", Type: models.MemberTypeEmployee, Role: "tech lead"},
+		{Name: "Bob", Type: models.MemberTypeContractor, Duration: 12, Tags: []string{"go", "external"}},
+	}
+
+	for _, member := range members {
+		// Act
+		verdict, err := rules.ValidateMember(context.Background(), member)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, verdict.Valid, member.Name)
+		assert.Empty(t, verdict.Reasons)
+	}
+}
+
+func TestRulesRejectMembers(t *testing.T) {
+	// Arrange
+	rules, err := ParseRules([]byte(rulesYAML))
+	require.NoError(t, err)
+	tests := []struct {
+		member *models.Member
+		want   models.ValidationErrors
+	}{
+		{
+			&models.Member{Name: "Alice", Type: models.MemberTypeEmployee, Role: "Astronaut"},
+			models.ValidationErrors{{Field: "role", Code: models.CodeForbidden, Message: `Role "Astronaut" is not allowed`}},
+		},
+		{
+			&models.Member{Name: "Bob", Type: models.MemberTypeContractor, Duration: 13, Tags: []string{"go"}},
+			models.ValidationErrors{
+				{Field: "duration", Code: models.CodeInvalid, Message: "Contracts must not last more than 12"},
+				{Field: "tags", Code: models.CodeRequired, Message: `Tag "external" is required for contractors`},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		// Act
+		verdict, err := rules.ValidateMember(context.Background(), test.member)
+
+		// Assert
+		require.NoError(t, err)
+		assert.False(t, verdict.Valid, test.member.Name)
+		assert.Equal(t, test.want, verdict.Reasons)
+	}
+}
+
+func TestEmptyRulesAcceptEveryMember(t *testing.T) {
+	// Arrange
+	rules, err := ParseRules(nil)
+	require.NoError(t, err)
+
+	// Act
+	verdict, err := rules.ValidateMember(context.Background(), &models.Member{Type: models.MemberTypeContractor, Duration: 600})
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, verdict.Valid)
+}
+
+func TestParseRulesRejectsInvalidRules(t *testing.T) {
+	tests := []string{
+		"max_contractor_duration: -1",
+		"required_tags:\n  intern: [school]",
+		"required_tags:\n  employee: ['  ']",
+		"allowed_roles: [Engineer]",
+		"roles: Engineer",
+	}
+
+	for _, test := range tests {
+		// Act
+		_, err := ParseRules([]byte(test))
+
+		// Assert
+		assert.Error(t, err, test)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(rulesYAML), 0o600))
+
+	// Act
+	rules, err := LoadRules(path)
+	_, missingErr := LoadRules(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, &Rules{
+		Roles:                 []string{"Software Engineer", "Tech Lead"},
+		MaxContractorDuration: 12,
+		RequiredTags:          map[string][]string{"contractor": {"external"}},
+	}, rules)
+	assert.ErrorIs(t, missingErr, os.ErrNotExist)
+}
@@ -0,0 +1,57 @@
+package validation
+
+import (
+	"codelit/internal/models"
+	"context"
+	"log"
+)
+
+// Validator gives a verdict on a member. An error means the validator could not
+// decide, not that the member is invalid.
+type Validator interface {
+	ValidateMember(ctx context.Context, member *models.Member) (*models.Verdict, error)
+}
+
+// chain asks its validators in turn.
+type chain []Validator
+
+// Chain returns a validator accepting the members that all the validators accept.
+// They are asked in order and the first rejection or error is returned, so that
+// cheap local validators should come first.
+func Chain(validators ...Validator) Validator {
+	return chain(validators)
+}
+
+func (c chain) ValidateMember(ctx context.Context, member *models.Member) (*models.Verdict, error) {
+	for _, validator := range c {
+		verdict, err := validator.ValidateMember(ctx, member)
+		if err != nil {
+			return nil, err
+		}
+		if !verdict.Valid {
+			return verdict, nil
+		}
+	}
+	return &models.Verdict{Valid: true, Reasons: models.ValidationErrors{}}, nil
+}
+
+// fallback asks its secondary validator when the primary one cannot decide.
+type fallback struct {
+	primary   Validator
+	secondary Validator
+}
+
+// Fallback returns a validator asking primary, or secondary when primary fails,
+// for instance local rules when the remote validation service is unavailable.
+func Fallback(primary, secondary Validator) Validator {
+	return fallback{primary: primary, secondary: secondary}
+}
+
+func (f fallback) ValidateMember(ctx context.Context, member *models.Member) (*models.Verdict, error) {
+	verdict, err := f.primary.ValidateMember(ctx, member)
+	if err == nil || ctx.Err() != nil {
+		return verdict, err
+	}
+	log.Printf("Falling back to the local rules for member %d: %v", member.ID, err)
+	return f.secondary.ValidateMember(ctx, member)
+}
@@ -0,0 +1,93 @@
+package validation
+
+import (
+	"codelit/internal/models"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// counting wraps a validator and counts the members it was asked about.
+func counting(validator Validator, calls *int) validatorFunc {
+	return func(ctx context.Context, member *models.Member) (*models.Verdict, error) {
+		*calls++
+		return validator.ValidateMember(ctx, member)
+	}
+}
+
+var unavailable = validatorFunc(func(ctx context.Context, member *models.Member) (*models.Verdict, error) {
+	return nil, errors.New("service unavailable")
+})
+
+func TestChainStopsAtFirstRejection(t *testing.T) {
+	// Arrange
+	rules := &Rules{MaxContractorDuration: 12}
+	calls := 0
+	validator := Chain(rules, counting(rejectContractors, &calls))
+
+	// Act
+	long, longErr := validator.ValidateMember(context.Background(), &models.Member{Type: models.MemberTypeContractor, Duration: 24})
+	short, shortErr := validator.ValidateMember(context.Background(), &models.Member{Type: models.MemberTypeContractor, Duration: 6})
+
+	// Assert
+	require.NoError(t, longErr)
+	require.NoError(t, shortErr)
+	assert.Equal(t, "Contracts must not last more than 12", long.Reasons.Error())
+	assert.Equal(t, "contractors are not allowed", short.Reasons.Error())
+	assert.Equal(t, 1, calls, "rejected members are not sent further")
+}
+
+func TestChainAcceptsWhenAllAccept(t *testing.T) {
+	// Arrange
+	validator := Chain(&Rules{}, rejectContractors)
+
+	// Act
+	verdict, err := validator.ValidateMember(context.Background(), &models.Member{Type: models.MemberTypeEmployee, Role: "Engineer"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, verdict.Valid)
+}
+
+func TestChainFailsWhenAValidatorFails(t *testing.T) {
+	// Act
+	_, err := Chain(&Rules{}, unavailable).ValidateMember(context.Background(), &models.Member{Type: models.MemberTypeEmployee})
+
+	// Assert
+	assert.EqualError(t, err, "service unavailable")
+}
+
+func TestFallback(t *testing.T) {
+	// Arrange
+	rules := &Rules{Roles: []string{"Engineer"}}
+	calls := 0
+	member := &models.Member{Type: models.MemberTypeEmployee, Role: "Astronaut"}
+
+	// Act
+	fromService, serviceErr := Fallback(rejectContractors, counting(rules, &calls)).ValidateMember(context.Background(), member)
+	fromRules, rulesErr := Fallback(unavailable, counting(rules, &calls)).ValidateMember(context.Background(), member)
+
+	// Assert
+	require.NoError(t, serviceErr)
+	require.NoError(t, rulesErr)
+	assert.True(t, fromService.Valid)
+	assert.False(t, fromRules.Valid)
+	assert.Equal(t, 1, calls, "the rules are only asked when the service fails")
+}
+
+func TestFallbackGivesUpWhenCancelled(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+
+	// Act
+	_, err := Fallback(unavailable, counting(&Rules{}, &calls)).ValidateMember(ctx, &models.Member{})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Zero(t, calls)
+}
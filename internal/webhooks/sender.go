@@ -0,0 +1,379 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// claimed is a delivery claimed by the Sender, with what it needs to post it.
+type claimed struct {
+	*Delivery
+	url    string
+	secret string
+}
+
+// Sender polls the pending deliveries of the active webhooks and posts them,
+// as long as the webhooks are still subscribed to their event types.
+// A delivery is done once the webhook answered with a 2xx status. Failed
+// deliveries are retried later with exponential backoff and are dead after the
+// last attempt. Posts cancelled by a shutdown are not attempts and are given
+// back. Like the outbox relay, deliveries claimed by a sender that stopped
+// before finishing them are claimed again once their lease expired.
+type Sender struct {
+	store  *Store
+	client *http.Client
+
+	interval       time.Duration
+	batchSize      int
+	lease          time.Duration
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	lock           bool
+}
+
+type Option func(*Sender)
+
+// WithInterval sets how long the sender waits before polling again when no
+// delivery was pending.
+func WithInterval(interval time.Duration) Option {
+	return func(s *Sender) {
+		s.interval = interval
+	}
+}
+
+// WithBatchSize sets how many deliveries are claimed and posted at a time.
+func WithBatchSize(size int) Option {
+	return func(s *Sender) {
+		s.batchSize = size
+	}
+}
+
+// WithTimeout sets how long a webhook has to answer. The lease of claimed
+// deliveries is a minute longer.
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Sender) {
+		s.client.Timeout = timeout
+		s.lease = timeout + time.Minute
+	}
+}
+
+// WithMaxAttempts sets how many times a delivery is posted before it is dead.
+func WithMaxAttempts(attempts int) Option {
+	return func(s *Sender) {
+		s.maxAttempts = attempts
+	}
+}
+
+// WithBackoff sets the wait before retrying a failed delivery, which doubles
+// after every attempt up to max.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(s *Sender) {
+		s.initialBackoff = initial
+		s.maxBackoff = max
+	}
+}
+
+// WithPrivateNetworks lets the sender post to loopback, private and link-local
+// addresses, which are refused by default so that webhooks cannot reach the
+// internal network. It is meant for local development and tests.
+func WithPrivateNetworks() Option {
+	return func(s *Sender) {
+		s.client.Transport = http.DefaultTransport
+	}
+}
+
+// WithoutRowLocks claims deliveries without FOR UPDATE SKIP LOCKED, for
+// databases such as SQLite which do not support it.
+func WithoutRowLocks() Option {
+	return func(s *Sender) {
+		s.lock = false
+	}
+}
+
+// NewSender returns a sender of the deliveries kept by store.
+func NewSender(store *Store, opts ...Option) *Sender {
+	s := &Sender{
+		store:          store,
+		client:         &http.Client{Timeout: 10 * time.Second, Transport: publicTransport()},
+		interval:       time.Second,
+		batchSize:      20,
+		lease:          10*time.Second + time.Minute,
+		maxAttempts:    10,
+		initialBackoff: 30 * time.Second,
+		maxBackoff:     time.Hour,
+		lock:           true,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// publicTransport returns a transport that only connects to public addresses,
+// checked once host names are resolved.
+func publicTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("webhooks may not connect to %s, which is not a public address", host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// Run posts the pending deliveries until ctx is done, then waits for the
+// deliveries being posted and returns nil.
+func (s *Sender) Run(ctx context.Context) error {
+	for {
+		sent, err := s.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Could not send the webhook deliveries: %v", err)
+		}
+		if err == nil && sent == s.batchSize {
+			// There are probably more deliveries waiting
+			continue
+		}
+
+		timer := time.NewTimer(s.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// Poll claims a batch of pending deliveries and posts them concurrently. It
+// returns the number of deliveries claimed.
+func (s *Sender) Poll(ctx context.Context) (int, error) {
+	deliveries, err := s.claim(ctx)
+	if err != nil || len(deliveries) == 0 {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery claimed) {
+			defer wg.Done()
+			s.send(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+// claim selects the next pending deliveries of active webhooks still subscribed
+// to their event type and leases them, so that other senders skip them until
+// they are done or the lease expires.
+func (s *Sender) claim(ctx context.Context) ([]claimed, error) {
+	tx, err := s.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := s.store.now().UTC()
+	query := `SELECT d.` + strings.ReplaceAll(deliveryColumns, ", ", ", d.") + `, w.url, w.secret
+	FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+	WHERE d.status = $1 AND d.next_attempt_at <= $2 AND w.active
+	AND ',' || w.event_types || ',' LIKE '%,' || d.event_type || ',%'
+	ORDER BY d.event_id, d.id LIMIT $3`
+	if s.lock {
+		query += " FOR UPDATE OF d SKIP LOCKED"
+	}
+	rows, err := tx.QueryContext(ctx, query, StatusPending, now, s.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []claimed{}
+	for rows.Next() {
+		c := claimed{}
+		if c.Delivery, err = scanDelivery(rows, &c.url, &c.secret); err != nil {
+			return nil, err
+		}
+		c.Attempts++
+		deliveries = append(deliveries, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	args := []interface{}{now.Add(s.lease)}
+	placeholders := []string{}
+	for _, delivery := range deliveries {
+		args = append(args, delivery.ID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = $1 WHERE id IN ("+
+		strings.Join(placeholders, ", ")+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, tx.Commit()
+}
+
+// send posts the delivery and records the outcome.
+func (s *Sender) send(ctx context.Context, delivery claimed) {
+	// The outcome is recorded even when ctx is done, so that delivered events
+	// are not posted again
+	recordCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	statusCode, err := s.post(ctx, delivery)
+	if err != nil && ctx.Err() != nil {
+		// The post was cancelled by the shutdown, which is not an attempt
+		if err := s.release(recordCtx, delivery.ID); err != nil {
+			log.Printf("Could not release delivery %d to webhook %d: %v", delivery.ID, delivery.WebhookID, err)
+		}
+		return
+	}
+	attempt := Attempt{
+		Attempt:     delivery.Attempts,
+		StatusCode:  statusCode,
+		DurationMS:  int(time.Since(start) / time.Millisecond),
+		AttemptedAt: s.store.now().UTC(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	status, next := StatusDelivered, attempt.AttemptedAt
+	switch {
+	case err == nil:
+	case delivery.Attempts >= s.maxAttempts:
+		log.Printf("Giving up on delivery %d of %s to webhook %d after %d attempts: %v",
+			delivery.ID, delivery.EventType, delivery.WebhookID, delivery.Attempts, err)
+		status = StatusDead
+	default:
+		status, next = StatusPending, attempt.AttemptedAt.Add(s.backoff(delivery.Attempts))
+	}
+	if err := s.record(recordCtx, delivery.ID, status, next, attempt); err != nil {
+		// The lease expires and the delivery is posted again
+		log.Printf("Could not record delivery %d to webhook %d: %v", delivery.ID, delivery.WebhookID, err)
+	}
+}
+
+// post sends the delivery to its webhook and returns the status it answered.
+func (s *Sender) post(ctx context.Context, delivery claimed) (int, error) {
+	body, err := json.Marshal(Body{
+		DeliveryID: delivery.ID,
+		EventID:    delivery.EventID,
+		Type:       delivery.EventType,
+		Member:     delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	now := s.store.now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "member-manager-webhooks")
+	req.Header.Set("X-Webhook-Id", strconv.Itoa(delivery.WebhookID))
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("X-Webhook-Signature", Sign(delivery.secret, now, body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	// Reading the body lets the connection be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// record logs the attempt and sets the status of the delivery. The attempt of a
+// delivery deleted in the meantime is not logged.
+func (s *Sender) record(ctx context.Context, id int64, status string, next time.Time, attempt Attempt) error {
+	tx, err := s.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var statusCode, lastError interface{}
+	if attempt.StatusCode != 0 {
+		statusCode = attempt.StatusCode
+	}
+	if attempt.Error != "" {
+		lastError = attempt.Error
+	}
+	var deliveredAt interface{}
+	if status == StatusDelivered {
+		deliveredAt = attempt.AttemptedAt
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE webhook_deliveries SET status = $1, next_attempt_at = $2,
+	last_status_code = $3, last_error = $4, delivered_at = $5 WHERE id = $6`,
+		status, next, statusCode, lastError, deliveredAt, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO webhook_attempts (delivery_id, attempt, status_code, error, duration_ms, attempted_at)
+	VALUES ($1, $2, $3, $4, $5, $6)`, id, attempt.Attempt, statusCode, lastError, attempt.DurationMS, attempt.AttemptedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// release gives back the attempt of a claimed delivery that was not posted and
+// ends its lease, so that it is posted again right away.
+func (s *Sender) release(ctx context.Context, id int64) error {
+	_, err := s.store.db.ExecContext(ctx, `UPDATE webhook_deliveries SET attempts = attempts - 1, next_attempt_at = $1
+	WHERE id = $2 AND status = $3`, s.store.now().UTC(), id, StatusPending)
+	return err
+}
+
+// backoff returns the wait before the attempt following the given one.
+func (s *Sender) backoff(attempt int) time.Duration {
+	backoff := s.initialBackoff
+	for i := 1; i < attempt && backoff < s.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > s.maxBackoff {
+		backoff = s.maxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,285 @@
+package webhooks
+
+import (
+	"codelit/internal/outbox"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a time the test moves forward.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// received is a request received by a receiver.
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver is a webhook answering with the given statuses in turn, then 204.
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, *[]received) {
+	var mu sync.Mutex
+	requests := []received{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, received{header: r.Header, body: body})
+		if len(requests) <= len(statuses) {
+			w.WriteHeader(statuses[len(requests)-1])
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestSenderPostsSignedDeliveries(t *testing.T) {
+	// Arrange
+	now := &clock{now: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewStore(newDB(t), WithClock(now.Now))
+	server, requests := receiver(t)
+	w := createWebhook(t, store, server.URL, outbox.MemberCreated)
+	require.NoError(t, store.HandleEvent(context.Background(), event(t, 7, outbox.MemberCreated, bob)))
+	sender := NewSender(store, WithPrivateNetworks(), WithoutRowLocks())
+
+	// Act
+	sent, err := sender.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, *requests, 1)
+	req := (*requests)[0]
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+	assert.Equal(t, strconv.Itoa(w.ID), req.header.Get("X-Webhook-Id"))
+	assert.Equal(t, outbox.MemberCreated, req.header.Get("X-Webhook-Event"))
+	assert.Equal(t, "1682942400", req.header.Get("X-Webhook-Timestamp"))
+	assert.Equal(t, Sign(w.Secret, now.Now(), req.body), req.header.Get("X-Webhook-Signature"))
+	body := Body{}
+	require.NoError(t, json.Unmarshal(req.body, &body))
+	assert.Equal(t, int64(7), body.EventID)
+	assert.Equal(t, outbox.MemberCreated, body.Type)
+	assert.JSONEq(t, `{"id":2,"name":"Bob","type":"contractor","duration":6,"version":1}`, string(body.Member))
+
+	delivery, err := store.Delivery(context.Background(), w.ID, body.DeliveryID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusDelivered, delivery.Status)
+	assert.Equal(t, http.StatusNoContent, delivery.LastStatusCode)
+	assert.Equal(t, now.Now(), *delivery.DeliveredAt)
+	if assert.Len(t, delivery.Log, 1) {
+		assert.Equal(t, 1, delivery.Log[0].Attempt)
+		assert.Equal(t, http.StatusNoContent, delivery.Log[0].StatusCode)
+	}
+}
+
+func TestSenderRetriesWithBackoff(t *testing.T) {
+	// Arrange
+	now := &clock{now: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewStore(newDB(t), WithClock(now.Now))
+	server, requests := receiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	w := createWebhook(t, store, server.URL, outbox.MemberCreated)
+	require.NoError(t, store.HandleEvent(context.Background(), event(t, 1, outbox.MemberCreated, bob)))
+	sender := NewSender(store, WithPrivateNetworks(), WithoutRowLocks(), WithBackoff(time.Second, time.Minute))
+
+	// Act
+	first, firstErr := sender.Poll(context.Background())
+	early, earlyErr := sender.Poll(context.Background())
+	now.Add(time.Second)
+	second, secondErr := sender.Poll(context.Background())
+	now.Add(time.Second)
+	stillWaiting, _ := sender.Poll(context.Background())
+	now.Add(time.Second)
+	third, thirdErr := sender.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, firstErr)
+	require.NoError(t, earlyErr)
+	require.NoError(t, secondErr)
+	require.NoError(t, thirdErr)
+	assert.Equal(t, []int{1, 0, 1, 0, 1}, []int{first, early, second, stillWaiting, third})
+	assert.Len(t, *requests, 3)
+	delivery, err := store.Delivery(context.Background(), w.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, StatusDelivered, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	if assert.Len(t, delivery.Log, 3) {
+		assert.Equal(t, http.StatusInternalServerError, delivery.Log[0].StatusCode)
+		assert.Equal(t, "webhook answered 500 Internal Server Error", delivery.Log[0].Error)
+		assert.Equal(t, http.StatusServiceUnavailable, delivery.Log[1].StatusCode)
+		assert.Empty(t, delivery.Log[2].Error)
+	}
+}
+
+func TestSenderGivesUp(t *testing.T) {
+	// Arrange
+	now := &clock{now: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewStore(newDB(t), WithClock(now.Now))
+	server, _ := receiver(t, http.StatusBadGateway, http.StatusBadGateway)
+	w := createWebhook(t, store, server.URL, outbox.MemberCreated)
+	require.NoError(t, store.HandleEvent(context.Background(), event(t, 1, outbox.MemberCreated, bob)))
+	sender := NewSender(store, WithPrivateNetworks(), WithoutRowLocks(), WithMaxAttempts(2), WithBackoff(time.Second, time.Second))
+
+	// Act
+	for i := 0; i < 3; i++ {
+		_, err := sender.Poll(context.Background())
+		require.NoError(t, err)
+		now.Add(time.Second)
+	}
+	dead, err := store.Delivery(context.Background(), w.ID, 1)
+	require.NoError(t, err)
+	replayed, replayErr := store.Replay(context.Background(), w.ID, 1)
+	require.NoError(t, replayErr)
+	sent, sendErr := sender.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, sendErr)
+	assert.Equal(t, StatusDead, dead.Status)
+	assert.Equal(t, 2, dead.Attempts)
+	assert.Equal(t, http.StatusBadGateway, dead.LastStatusCode)
+	assert.Nil(t, dead.NextAttemptAt)
+	assert.Equal(t, StatusPending, replayed.Status)
+	assert.Equal(t, 1, sent, "a replayed delivery is posted again")
+}
+
+func TestSenderSkipsInactiveWebhooks(t *testing.T) {
+	// Arrange
+	store := NewStore(newDB(t))
+	server, requests := receiver(t)
+	w := createWebhook(t, store, server.URL, outbox.MemberCreated)
+	require.NoError(t, store.HandleEvent(context.Background(), event(t, 1, outbox.MemberCreated, bob)))
+	w.Active = false
+	require.NoError(t, store.Update(context.Background(), w))
+	sender := NewSender(store, WithPrivateNetworks(), WithoutRowLocks())
+
+	// Act
+	sent, err := sender.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Empty(t, *requests)
+}
+
+func TestSenderSkipsUnsubscribedEventTypes(t *testing.T) {
+	// Arrange
+	store := NewStore(newDB(t))
+	server, requests := receiver(t)
+	w := createWebhook(t, store, server.URL, outbox.MemberCreated, outbox.MemberDeleted)
+	require.NoError(t, store.HandleEvent(context.Background(), event(t, 1, outbox.MemberCreated, bob)))
+	require.NoError(t, store.HandleEvent(context.Background(), event(t, 2, outbox.MemberDeleted, bob)))
+	w.EventTypes = []string{outbox.MemberDeleted}
+	require.NoError(t, store.Update(context.Background(), w))
+	sender := NewSender(store, WithPrivateNetworks(), WithoutRowLocks())
+
+	// Act
+	sent, err := sender.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	if assert.Len(t, *requests, 1) {
+		assert.Equal(t, outbox.MemberDeleted, (*requests)[0].header.Get("X-Webhook-Event"))
+	}
+}
+
+func TestSenderRefusesPrivateAddresses(t *testing.T) {
+	// Arrange
+	store := NewStore(newDB(t))
+	server, requests := receiver(t)
+	w := createWebhook(t, store, server.URL, outbox.MemberCreated)
+	require.NoError(t, store.HandleEvent(context.Background(), event(t, 1, outbox.MemberCreated, bob)))
+	sender := NewSender(store, WithoutRowLocks())
+
+	// Act
+	_, err := sender.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, *requests)
+	delivery, err := store.Delivery(context.Background(), w.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, delivery.Status)
+	assert.Contains(t, delivery.LastError, "not a public address")
+}
+
+func TestSenderUnreachableWebhook(t *testing.T) {
+	// Arrange
+	store := NewStore(newDB(t))
+	server, _ := receiver(t)
+	server.Close()
+	w := createWebhook(t, store, server.URL, outbox.MemberCreated)
+	require.NoError(t, store.HandleEvent(context.Background(), event(t, 1, outbox.MemberCreated, bob)))
+	sender := NewSender(store, WithPrivateNetworks(), WithoutRowLocks(), WithTimeout(time.Second))
+
+	// Act
+	_, err := sender.Poll(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	delivery, err := store.Delivery(context.Background(), w.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, delivery.Status)
+	assert.Equal(t, 0, delivery.LastStatusCode)
+	assert.Contains(t, delivery.LastError, "connection refused")
+	if assert.Len(t, delivery.Log, 1) {
+		assert.Zero(t, delivery.Log[0].StatusCode)
+	}
+}
+
+func TestSenderShutdownIsNotAnAttempt(t *testing.T) {
+	// Arrange
+	now := &clock{now: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewStore(newDB(t), WithClock(now.Now))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The sender shuts down while the webhook is answering
+		io.Copy(io.Discard, r.Body)
+		cancel()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	w := createWebhook(t, store, server.URL, outbox.MemberCreated)
+	require.NoError(t, store.HandleEvent(context.Background(), event(t, 1, outbox.MemberCreated, bob)))
+	sender := NewSender(store, WithPrivateNetworks(), WithoutRowLocks(), WithMaxAttempts(1))
+
+	// Act
+	sent, err := sender.Poll(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	delivery, err := store.Delivery(context.Background(), w.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, delivery.Status)
+	assert.Equal(t, 0, delivery.Attempts)
+	assert.Equal(t, now.Now(), *delivery.NextAttemptAt)
+	assert.Empty(t, delivery.LastError)
+	assert.Empty(t, delivery.Log)
+}
@@ -0,0 +1,321 @@
+package webhooks
+
+import (
+	"codelit/internal/outbox"
+	"codelit/internal/repositories"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store keeps the webhooks and their deliveries in the database of the outbox.
+// It is safe for concurrent use.
+type Store struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+type StoreOption func(*Store)
+
+// WithClock sets the clock of the timestamps of webhooks and deliveries.
+func WithClock(now func() time.Time) StoreOption {
+	return func(s *Store) {
+		s.now = now
+	}
+}
+
+func NewStore(db *sql.DB, opts ...StoreOption) *Store {
+	s := &Store{db: db, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func notFound(what string) error {
+	return &repositories.Error{Kind: repositories.ErrNotFound, Err: errors.New(what + " not found")}
+}
+
+const webhookColumns = "id, url, secret, event_types, active, created_at, updated_at"
+
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*Webhook, error) {
+	w := &Webhook{}
+	var eventTypes string
+	if err := row.Scan(&w.ID, &w.URL, &w.Secret, &eventTypes, &w.Active, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	w.EventTypes = strings.Split(eventTypes, ",")
+	return w, nil
+}
+
+// Create stores a new webhook, with a random secret when it has none.
+func (s *Store) Create(ctx context.Context, w *Webhook) error {
+	if w.Secret == "" {
+		secret, err := NewSecret()
+		if err != nil {
+			return err
+		}
+		w.Secret = secret
+	}
+	w.CreatedAt = s.now().UTC()
+	w.UpdatedAt = w.CreatedAt
+	err := s.db.QueryRowContext(ctx, `INSERT INTO webhooks (url, secret, event_types, active, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		w.URL, w.Secret, strings.Join(w.EventTypes, ","), w.Active, w.CreatedAt, w.UpdatedAt).Scan(&w.ID)
+	return repositories.DBError(ctx, err)
+}
+
+func (s *Store) Get(ctx context.Context, id int) (*Webhook, error) {
+	w, err := scanWebhook(s.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("webhook")
+	}
+	if err != nil {
+		return nil, repositories.DBError(ctx, err)
+	}
+	return w, nil
+}
+
+// List returns every webhook, the oldest first.
+func (s *Store) List(ctx context.Context) ([]*Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, repositories.DBError(ctx, err)
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, repositories.DBError(ctx, err)
+		}
+		webhooks = append(webhooks, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, repositories.DBError(ctx, err)
+	}
+	return webhooks, nil
+}
+
+// Update replaces the URL, event types and state of a webhook, and its secret
+// when one is given. Queued deliveries of the event types no longer subscribed
+// to are not sent, like those of inactive webhooks.
+func (s *Store) Update(ctx context.Context, w *Webhook) error {
+	w.UpdatedAt = s.now().UTC()
+	query := "UPDATE webhooks SET url = $1, event_types = $2, active = $3, updated_at = $4"
+	args := []interface{}{w.URL, strings.Join(w.EventTypes, ","), w.Active, w.UpdatedAt}
+	if w.Secret != "" {
+		args = append(args, w.Secret)
+		query += ", secret = $5"
+	}
+	args = append(args, w.ID)
+	query += " WHERE id = $" + strconv.Itoa(len(args)) + " RETURNING created_at"
+
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&w.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound("webhook")
+	}
+	return repositories.DBError(ctx, err)
+}
+
+// Delete removes a webhook along with its deliveries and their log.
+func (s *Store) Delete(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return repositories.DBError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	// Foreign keys are not enforced by SQLite, the rows are deleted explicitly
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_attempts
+	WHERE delivery_id IN (SELECT id FROM webhook_deliveries WHERE webhook_id = $1)`, id); err != nil {
+		return repositories.DBError(ctx, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = $1", id); err != nil {
+		return repositories.DBError(ctx, err)
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return repositories.DBError(ctx, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return repositories.DBError(ctx, err)
+	} else if n == 0 {
+		return notFound("webhook")
+	}
+	return repositories.DBError(ctx, tx.Commit())
+}
+
+// HandleEvent queues a delivery of the event for every active webhook subscribed
+// to its type. It is called by the outbox relay, and queues a single delivery
+// per webhook when the same event is handled again.
+func (s *Store) HandleEvent(ctx context.Context, event outbox.Event) error {
+	webhooks, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := s.now().UTC()
+	for _, w := range webhooks {
+		if !w.Active || !w.Subscribed(event.Type) {
+			continue
+		}
+		_, err := s.db.ExecContext(ctx, `INSERT INTO webhook_deliveries
+		(webhook_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (webhook_id, event_id) DO NOTHING`,
+			w.ID, event.ID, event.Type, string(event.Payload), StatusPending, now, now)
+		if err != nil {
+			return repositories.DBError(ctx, err)
+		}
+	}
+	return nil
+}
+
+// DeliveryFilter selects the deliveries of a webhook, the latest first.
+type DeliveryFilter struct {
+	// Status is any status when empty
+	Status string
+	// Before is the id the deliveries precede, for the next page
+	Before int64
+	Limit  int
+}
+
+const deliveryColumns = "id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at"
+
+// scanDelivery scans the deliveryColumns, followed by the extra columns of the
+// row into extra.
+func scanDelivery(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*Delivery, error) {
+	d := &Delivery{}
+	var payload []byte
+	var nextAttemptAt, deliveredAt sql.NullTime
+	var lastStatusCode sql.NullInt64
+	var lastError sql.NullString
+	dest := []interface{}{&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts, &nextAttemptAt,
+		&lastStatusCode, &lastError, &d.CreatedAt, &deliveredAt}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	if d.Status == StatusPending && nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	d.LastStatusCode = int(lastStatusCode.Int64)
+	d.LastError = lastError.String
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return d, nil
+}
+
+// Deliveries returns a page of the deliveries of a webhook, the latest first.
+func (s *Store) Deliveries(ctx context.Context, webhookID int, filter DeliveryFilter) ([]*Delivery, error) {
+	if _, err := s.Get(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE webhook_id = $1"
+	args := []interface{}{webhookID}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += " AND status = $" + strconv.Itoa(len(args))
+	}
+	if filter.Before > 0 {
+		args = append(args, filter.Before)
+		query += " AND id < $" + strconv.Itoa(len(args))
+	}
+	args = append(args, filter.Limit)
+	query += " ORDER BY id DESC LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, repositories.DBError(ctx, err)
+	}
+	defer rows.Close()
+
+	deliveries := []*Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, repositories.DBError(ctx, err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, repositories.DBError(ctx, err)
+	}
+	return deliveries, nil
+}
+
+// Delivery returns a delivery of a webhook with the log of its attempts.
+func (s *Store) Delivery(ctx context.Context, webhookID int, id int64) (*Delivery, error) {
+	d, err := scanDelivery(s.db.QueryRowContext(ctx, "SELECT "+deliveryColumns+
+		" FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2", id, webhookID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound("delivery")
+	}
+	if err != nil {
+		return nil, repositories.DBError(ctx, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT attempt, status_code, error, duration_ms, attempted_at
+	FROM webhook_attempts WHERE delivery_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, repositories.DBError(ctx, err)
+	}
+	defer rows.Close()
+
+	d.Log = []Attempt{}
+	for rows.Next() {
+		a := Attempt{}
+		var statusCode sql.NullInt64
+		var attemptErr sql.NullString
+		if err := rows.Scan(&a.Attempt, &statusCode, &attemptErr, &a.DurationMS, &a.AttemptedAt); err != nil {
+			return nil, repositories.DBError(ctx, err)
+		}
+		a.StatusCode = int(statusCode.Int64)
+		a.Error = attemptErr.String
+		d.Log = append(d.Log, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, repositories.DBError(ctx, err)
+	}
+	return d, nil
+}
+
+// Replay queues a delivery of a webhook again, whatever its status, with a new
+// series of attempts. Its log is kept.
+func (s *Store) Replay(ctx context.Context, webhookID int, id int64) (*Delivery, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries
+	SET status = $1, attempts = 0, next_attempt_at = $2, delivered_at = NULL WHERE id = $3 AND webhook_id = $4`,
+		StatusPending, s.now().UTC(), id, webhookID)
+	if err != nil {
+		return nil, repositories.DBError(ctx, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, repositories.DBError(ctx, err)
+	} else if n == 0 {
+		return nil, notFound("delivery")
+	}
+	return s.Delivery(ctx, webhookID, id)
+}
+
+// ReplayDead queues the dead deliveries of a webhook again and returns how many
+// there were.
+func (s *Store) ReplayDead(ctx context.Context, webhookID int) (int, error) {
+	if _, err := s.Get(ctx, webhookID); err != nil {
+		return 0, err
+	}
+	res, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries
+	SET status = $1, attempts = 0, next_attempt_at = $2 WHERE webhook_id = $3 AND status = $4`,
+		StatusPending, s.now().UTC(), webhookID, StatusDead)
+	if err != nil {
+		return 0, repositories.DBError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), repositories.DBError(ctx, err)
+}
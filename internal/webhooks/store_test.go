@@ -0,0 +1,254 @@
+package webhooks
+
+import (
+	"codelit/db"
+	"codelit/internal/migrations"
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"codelit/internal/repositories"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// newDB returns a migrated SQLite database, closed when the test ends.
+func newDB(t *testing.T) *sql.DB {
+	files, err := db.Migrations("sqlite")
+	require.NoError(t, err)
+	conn, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "members.db"))
+	require.NoError(t, err)
+	conn.SetMaxOpenConns(1)
+	t.Cleanup(func() { conn.Close() })
+
+	runner, err := migrations.NewRunner(conn, files, migrations.WithoutLock())
+	require.NoError(t, err)
+	_, err = runner.Up(context.Background())
+	require.NoError(t, err)
+	return conn
+}
+
+// createWebhook stores an active webhook of the URL subscribed to eventTypes.
+func createWebhook(t *testing.T, store *Store, url string, eventTypes ...string) *Webhook {
+	w := &Webhook{URL: url, EventTypes: eventTypes, Active: true}
+	require.NoError(t, store.Create(context.Background(), w))
+	return w
+}
+
+// event returns an event of the given id about the member.
+func event(t *testing.T, id int64, eventType string, member *models.Member) outbox.Event {
+	payload, err := json.Marshal(member)
+	require.NoError(t, err)
+	return outbox.Event{ID: id, Type: eventType, MemberID: member.ID, Payload: payload}
+}
+
+var bob = &models.Member{ID: 2, Name: "Bob", Type: models.MemberTypeContractor, Duration: 6, Version: 1}
+
+func TestCreateAndGetWebhook(t *testing.T) {
+	// Arrange
+	store := NewStore(newDB(t))
+
+	// Act
+	created := createWebhook(t, store, "https://hr.example.com/hooks", outbox.MemberCreated, outbox.MemberDeleted)
+	got, err := store.Get(context.Background(), created.ID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, created.ID)
+	assert.Len(t, created.Secret, 64)
+	assert.Equal(t, created.Secret, got.Secret)
+	assert.Equal(t, []string{outbox.MemberCreated, outbox.MemberDeleted}, got.EventTypes)
+	assert.True(t, got.Active)
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	// Arrange
+	store := NewStore(newDB(t))
+	created := createWebhook(t, store, "https://hr.example.com/hooks", outbox.MemberCreated)
+	secret := created.Secret
+
+	// Act
+	updateErr := store.Update(context.Background(), &Webhook{ID: created.ID, URL: "https://hr.example.com/v2", EventTypes: []string{outbox.MemberUpdated}})
+	got, err := store.Get(context.Background(), created.ID)
+	missingErr := store.Update(context.Background(), &Webhook{ID: 42, URL: "https://hr.example.com", EventTypes: []string{outbox.MemberUpdated}})
+
+	// Assert
+	require.NoError(t, updateErr)
+	require.NoError(t, err)
+	assert.Equal(t, "https://hr.example.com/v2", got.URL)
+	assert.Equal(t, []string{outbox.MemberUpdated}, got.EventTypes)
+	assert.False(t, got.Active)
+	assert.Equal(t, secret, got.Secret, "the secret is kept when none is given")
+	assert.ErrorIs(t, missingErr, repositories.ErrNotFound)
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	store := NewStore(conn)
+	w := createWebhook(t, store, "https://hr.example.com/hooks", outbox.MemberCreated)
+	require.NoError(t, store.HandleEvent(context.Background(), event(t, 1, outbox.MemberCreated, bob)))
+
+	// Act
+	err := store.Delete(context.Background(), w.ID)
+	againErr := store.Delete(context.Background(), w.ID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.ErrorIs(t, againErr, repositories.ErrNotFound)
+	var deliveries int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM webhook_deliveries").Scan(&deliveries))
+	assert.Equal(t, 0, deliveries)
+}
+
+func TestHandleEventQueuesDeliveries(t *testing.T) {
+	// Arrange
+	store := NewStore(newDB(t))
+	hr := createWebhook(t, store, "https://hr.example.com/hooks", outbox.MemberCreated)
+	payroll := createWebhook(t, store, "https://payroll.example.com/hooks", outbox.MemberDeleted)
+	inactive := &Webhook{URL: "https://old.example.com/hooks", EventTypes: []string{outbox.MemberCreated}}
+	require.NoError(t, store.Create(context.Background(), inactive))
+	created := event(t, 1, outbox.MemberCreated, bob)
+
+	// Act
+	err := store.HandleEvent(context.Background(), created)
+	againErr := store.HandleEvent(context.Background(), created)
+
+	// Assert
+	require.NoError(t, err)
+	require.NoError(t, againErr)
+	deliveries, err := store.Deliveries(context.Background(), hr.ID, DeliveryFilter{Limit: 10})
+	require.NoError(t, err)
+	if assert.Len(t, deliveries, 1, "a single delivery is queued for an event handled twice") {
+		assert.Equal(t, int64(1), deliveries[0].EventID)
+		assert.Equal(t, outbox.MemberCreated, deliveries[0].EventType)
+		assert.Equal(t, StatusPending, deliveries[0].Status)
+		assert.JSONEq(t, string(created.Payload), string(deliveries[0].Payload))
+	}
+	for _, w := range []*Webhook{payroll, inactive} {
+		deliveries, err := store.Deliveries(context.Background(), w.ID, DeliveryFilter{Limit: 10})
+		require.NoError(t, err)
+		assert.Empty(t, deliveries, w.URL)
+	}
+}
+
+func TestDeliveriesFilter(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	store := NewStore(conn)
+	w := createWebhook(t, store, "https://hr.example.com/hooks", outbox.MemberCreated)
+	for id := int64(1); id <= 3; id++ {
+		require.NoError(t, store.HandleEvent(context.Background(), event(t, id, outbox.MemberCreated, bob)))
+	}
+	_, err := conn.Exec("UPDATE webhook_deliveries SET status = $1 WHERE event_id = 2", StatusDead)
+	require.NoError(t, err)
+
+	// Act
+	page, pageErr := store.Deliveries(context.Background(), w.ID, DeliveryFilter{Limit: 2})
+	next, nextErr := store.Deliveries(context.Background(), w.ID, DeliveryFilter{Limit: 2, Before: page[1].ID})
+	dead, deadErr := store.Deliveries(context.Background(), w.ID, DeliveryFilter{Status: StatusDead, Limit: 10})
+	_, missingErr := store.Deliveries(context.Background(), 42, DeliveryFilter{Limit: 10})
+
+	// Assert
+	require.NoError(t, pageErr)
+	require.NoError(t, nextErr)
+	require.NoError(t, deadErr)
+	assert.Equal(t, []int64{3, 2}, eventIDs(page))
+	assert.Equal(t, []int64{1}, eventIDs(next))
+	assert.Equal(t, []int64{2}, eventIDs(dead))
+	assert.ErrorIs(t, missingErr, repositories.ErrNotFound)
+}
+
+func eventIDs(deliveries []*Delivery) []int64 {
+	ids := []int64{}
+	for _, d := range deliveries {
+		ids = append(ids, d.EventID)
+	}
+	return ids
+}
+
+func TestReplay(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	store := NewStore(conn)
+	w := createWebhook(t, store, "https://hr.example.com/hooks", outbox.MemberCreated)
+	for id := int64(1); id <= 3; id++ {
+		require.NoError(t, store.HandleEvent(context.Background(), event(t, id, outbox.MemberCreated, bob)))
+	}
+	_, err := conn.Exec("UPDATE webhook_deliveries SET status = $1, attempts = 10", StatusDead)
+	require.NoError(t, err)
+
+	// Act
+	replayed, replayErr := store.Replay(context.Background(), w.ID, 1)
+	_, otherErr := store.Replay(context.Background(), w.ID+1, 2)
+	n, deadErr := store.ReplayDead(context.Background(), w.ID)
+
+	// Assert
+	require.NoError(t, replayErr)
+	require.NoError(t, deadErr)
+	assert.Equal(t, StatusPending, replayed.Status)
+	assert.Equal(t, 0, replayed.Attempts)
+	assert.NotNil(t, replayed.NextAttemptAt)
+	assert.ErrorIs(t, otherErr, repositories.ErrNotFound, "deliveries are replayed through their webhook")
+	assert.Equal(t, 2, n)
+}
+
+func TestPurgeRemovesDeliveries(t *testing.T) {
+	// Arrange
+	conn := newDB(t)
+	store := NewStore(conn)
+	repo := repositories.NewSQLiteRepository(conn)
+	ctx := context.Background()
+	w := createWebhook(t, store, "https://hr.example.com/hooks", outbox.MemberCreated)
+	alice := &models.Member{Name: "Alice", Type: models.MemberTypeEmployee, Role: "engineer"}
+	bob := &models.Member{Name: "Bob", Type: models.MemberTypeContractor, Duration: 6}
+	for i, member := range []*models.Member{alice, bob} {
+		require.NoError(t, repo.CreateMember(ctx, member))
+		require.NoError(t, store.HandleEvent(ctx, event(t, int64(i+1), outbox.MemberCreated, member)))
+	}
+	_, err := conn.Exec("INSERT INTO webhook_attempts (delivery_id, attempt, status_code, duration_ms) SELECT id, 1, 500, 10 FROM webhook_deliveries")
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteMember(ctx, bob.ID, 0))
+
+	// Act
+	purged, err := repo.PurgeDeletedMembers(ctx, time.Now().Add(time.Hour))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	deliveries, err := store.Deliveries(ctx, w.ID, DeliveryFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, eventIDs(deliveries), "only the deliveries about Alice are kept")
+	var attempts, events int
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM webhook_attempts").Scan(&attempts))
+	require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM outbox WHERE member_id = $1", bob.ID).Scan(&events))
+	assert.Equal(t, 1, attempts)
+	assert.Equal(t, 0, events, "the events about Bob are purged with him")
+}
+
+func TestStoreClassifiesDatabaseErrors(t *testing.T) {
+	// Arrange
+	conn, mock, _ := sqlmock.New()
+	defer conn.Close()
+	mock.ExpectQuery("SELECT (.+) FROM webhooks").WillReturnError(&pq.Error{Code: "08006"})
+	store := NewStore(conn)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	_, unavailableErr := store.List(context.Background())
+	_, canceledErr := NewStore(newDB(t)).List(ctx)
+
+	// Assert
+	assert.ErrorIs(t, unavailableErr, repositories.ErrUnavailable)
+	assert.ErrorIs(t, canceledErr, context.Canceled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
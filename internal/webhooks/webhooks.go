@@ -0,0 +1,195 @@
+// Package webhooks delivers the member events to the outside services that
+// subscribed to them. The Store queues a delivery of every outbox event for each
+// webhook subscribed to its type, and the Sender posts the queued deliveries,
+// signed with the secret of their webhook, retrying them with backoff until they
+// are delivered or dead.
+package webhooks
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of a delivery. Deliveries are pending until the webhook answered with
+// a 2xx status, and dead once every attempt failed.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// EventTypes are the event types webhooks can subscribe to.
+var EventTypes = []string{
+	outbox.MemberCreated,
+	outbox.MemberUpdated,
+	outbox.MemberDeleted,
+	outbox.MemberRestored,
+	outbox.MemberRevalidated,
+	outbox.MemberValidated,
+}
+
+const (
+	maxURLLength    = 2048
+	minSecretLength = 16
+)
+
+// Webhook is the subscription of a URL to member events.
+type Webhook struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+	// Secret signs the deliveries. It is only returned when the webhook is
+	// created, and generated when none is given.
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Subscribed tells whether the webhook receives the events of the given type.
+func (w *Webhook) Subscribed(eventType string) bool {
+	for _, subscribed := range w.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the URL, secret and event types of the webhook and returns all
+// violations at once as models.ValidationErrors, or nil when it is valid. URLs
+// of loopback, private or link-local hosts are only valid with allowPrivate, so
+// that webhooks cannot reach the internal network. Host names resolving to such
+// addresses are refused by the Sender.
+func (w *Webhook) Validate(allowPrivate bool) error {
+	errs := models.ValidationErrors{}
+
+	if w.URL == "" {
+		errs = append(errs, models.FieldError{Field: "url", Code: models.CodeRequired, Message: "Webhooks must have a URL"})
+	} else if len(w.URL) > maxURLLength {
+		errs = append(errs, models.FieldError{Field: "url", Code: models.CodeTooLong, Message: fmt.Sprintf("URLs must be at most %d characters", maxURLLength)})
+	} else if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, models.FieldError{Field: "url", Code: models.CodeInvalid, Message: "URLs must be absolute http or https URLs"})
+	} else if !allowPrivate && privateHost(u.Hostname()) {
+		errs = append(errs, models.FieldError{Field: "url", Code: models.CodeForbidden, Message: "URLs must not point to a loopback, private or link-local address"})
+	}
+
+	if w.Secret != "" && len(w.Secret) < minSecretLength {
+		errs = append(errs, models.FieldError{Field: "secret", Code: models.CodeInvalid, Message: fmt.Sprintf("Secrets must be at least %d characters", minSecretLength)})
+	}
+
+	if len(w.EventTypes) == 0 {
+		errs = append(errs, models.FieldError{Field: "event_types", Code: models.CodeRequired, Message: "Webhooks must subscribe to at least one event type"})
+	}
+	seen := map[string]bool{}
+	for _, eventType := range w.EventTypes {
+		switch {
+		case !isEventType(eventType):
+			errs = append(errs, models.FieldError{Field: "event_types", Code: models.CodeInvalid,
+				Message: fmt.Sprintf("Unknown event type %q, please use %s", eventType, strings.Join(EventTypes, ", "))})
+		case seen[eventType]:
+			errs = append(errs, models.FieldError{Field: "event_types", Code: models.CodeDuplicate, Message: fmt.Sprintf("Event type %q is repeated", eventType)})
+		}
+		seen[eventType] = true
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// privateHost tells whether the host of a URL is a loopback, private or
+// link-local address, or localhost.
+func privateHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && !publicIP(ip)
+}
+
+// publicIP tells whether ip may be reached by webhooks.
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast()
+}
+
+func isEventType(eventType string) bool {
+	for _, known := range EventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is an event queued for a webhook, and the outcome of its latest
+// attempt.
+type Delivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	EventID        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	// Log is filled by Store.Delivery only
+	Log []Attempt `json:"log,omitempty"`
+}
+
+// Attempt is the log of one attempt to deliver an event. StatusCode is zero when
+// the webhook could not be reached.
+type Attempt struct {
+	Attempt     int       `json:"attempt"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMS  int       `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// Body is the JSON body posted to webhooks.
+type Body struct {
+	DeliveryID int64           `json:"delivery_id"`
+	EventID    int64           `json:"event_id"`
+	Type       string          `json:"type"`
+	Member     json.RawMessage `json:"member"`
+}
+
+// NewSecret returns a random secret to sign deliveries with.
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Sign returns the X-Webhook-Signature header of a body sent at the given time:
+// "sha256=" followed by the hex HMAC-SHA256, keyed with the secret, of the unix
+// timestamp, a dot and the body. Receivers compute it again to check that the
+// body comes from this API and was not replayed long after it was sent.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
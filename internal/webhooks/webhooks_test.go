@@ -0,0 +1,129 @@
+package webhooks
+
+import (
+	"codelit/internal/models"
+	"codelit/internal/outbox"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		webhook      Webhook
+		allowPrivate bool
+		want         models.ValidationErrors
+	}{
+		{
+			name:    "valid",
+			webhook: Webhook{URL: "https://hr.example.com/hooks", EventTypes: []string{outbox.MemberCreated, outbox.MemberDeleted}},
+		},
+		{
+			name:    "missing fields",
+			webhook: Webhook{},
+			want: models.ValidationErrors{
+				{Field: "url", Code: models.CodeRequired, Message: "Webhooks must have a URL"},
+				{Field: "event_types", Code: models.CodeRequired, Message: "Webhooks must subscribe to at least one event type"},
+			},
+		},
+		{
+			name:    "relative url",
+			webhook: Webhook{URL: "/hooks", EventTypes: []string{outbox.MemberCreated}},
+			want: models.ValidationErrors{
+				{Field: "url", Code: models.CodeInvalid, Message: "URLs must be absolute http or https URLs"},
+			},
+		},
+		{
+			name:    "other scheme",
+			webhook: Webhook{URL: "ftp://hr.example.com", EventTypes: []string{outbox.MemberCreated}},
+			want: models.ValidationErrors{
+				{Field: "url", Code: models.CodeInvalid, Message: "URLs must be absolute http or https URLs"},
+			},
+		},
+		{
+			name:    "loopback address",
+			webhook: Webhook{URL: "http://127.0.0.1:8080/hooks", EventTypes: []string{outbox.MemberCreated}},
+			want: models.ValidationErrors{
+				{Field: "url", Code: models.CodeForbidden, Message: "URLs must not point to a loopback, private or link-local address"},
+			},
+		},
+		{
+			name:    "localhost",
+			webhook: Webhook{URL: "http://LocalHost./hooks", EventTypes: []string{outbox.MemberCreated}},
+			want: models.ValidationErrors{
+				{Field: "url", Code: models.CodeForbidden, Message: "URLs must not point to a loopback, private or link-local address"},
+			},
+		},
+		{
+			name:    "private address",
+			webhook: Webhook{URL: "https://10.0.3.7/hooks", EventTypes: []string{outbox.MemberCreated}},
+			want: models.ValidationErrors{
+				{Field: "url", Code: models.CodeForbidden, Message: "URLs must not point to a loopback, private or link-local address"},
+			},
+		},
+		{
+			name:    "cloud metadata address",
+			webhook: Webhook{URL: "http://169.254.169.254/latest/meta-data", EventTypes: []string{outbox.MemberCreated}},
+			want: models.ValidationErrors{
+				{Field: "url", Code: models.CodeForbidden, Message: "URLs must not point to a loopback, private or link-local address"},
+			},
+		},
+		{
+			name:    "ipv6 loopback",
+			webhook: Webhook{URL: "http://[::1]/hooks", EventTypes: []string{outbox.MemberCreated}},
+			want: models.ValidationErrors{
+				{Field: "url", Code: models.CodeForbidden, Message: "URLs must not point to a loopback, private or link-local address"},
+			},
+		},
+		{
+			name:         "private address allowed",
+			webhook:      Webhook{URL: "http://127.0.0.1:8080/hooks", EventTypes: []string{outbox.MemberCreated}},
+			allowPrivate: true,
+		},
+		{
+			name:    "short secret",
+			webhook: Webhook{URL: "http://payroll", Secret: "hunter2", EventTypes: []string{outbox.MemberCreated}},
+			want: models.ValidationErrors{
+				{Field: "secret", Code: models.CodeInvalid, Message: "Secrets must be at least 16 characters"},
+			},
+		},
+		{
+			name:    "unknown and repeated event types",
+			webhook: Webhook{URL: "http://payroll", EventTypes: []string{"member.renamed", outbox.MemberDeleted, outbox.MemberDeleted}},
+			want: models.ValidationErrors{
+				{Field: "event_types", Code: models.CodeInvalid, Message: `Unknown event type "member.renamed", please use member.created, member.updated, member.deleted, member.restored, member.revalidated, member.validated`},
+				{Field: "event_types", Code: models.CodeDuplicate, Message: `Event type "member.deleted" is repeated`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := tt.webhook.Validate(tt.allowPrivate)
+
+			// Assert
+			if tt.want == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Arrange
+	timestamp := time.Unix(1700000000, 0)
+
+	// Act
+	signature := Sign("secret", timestamp, []byte(`{"event_id":1}`))
+
+	// Assert
+	// echo -n '1700000000.{"event_id":1}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=dd50adb138aae6c63e07ca88318bb0ffda13bcba001bd50739b8d68637c1aafe", signature)
+	assert.NotEqual(t, signature, Sign("other secret", timestamp, []byte(`{"event_id":1}`)))
+	assert.NotEqual(t, signature, Sign("secret", timestamp.Add(time.Second), []byte(`{"event_id":1}`)))
+}
@@ -1,18 +1,47 @@
 package main
 
 import (
+	"codelit/db"
 	"codelit/internal/api"
+	grpcclient "codelit/internal/client"
+	"codelit/internal/fakevalidator"
+	"codelit/internal/migrations"
+	"codelit/internal/outbox"
+	"codelit/internal/purge"
 	"codelit/internal/repositories"
+	grpcserver "codelit/internal/server"
+	"codelit/internal/validation"
+	"codelit/internal/webhooks"
+	"context"
 	"database/sql"
+	"errors"
+	"expvar"
+	"flag"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
+// Usage:
+//
+//	main [-migrate]           serve the API, applying pending migrations first with -migrate
+//	main migrate up           apply pending migrations and exit
+//	main migrate down [n]     revert the last n migrations (default 1) and exit
+//	main fake-validator [-addr :9000] [-config file.yaml]
+//	                          serve a fake validation service for local development
 func main() {
 	// Load environment variables from .env file
 	err := godotenv.Load()
@@ -20,26 +49,418 @@ func main() {
 		log.Fatal("Error loading .env file:", err)
 	}
 
-	dbHost := os.Getenv("DOCKER_INTERNAL")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
+	migrateOnStart := flag.Bool("migrate", os.Getenv("MIGRATE_ON_START") == "true", "apply pending database migrations before serving")
+	flag.Parse()
+
+	if flag.Arg(0) == "fake-validator" {
+		if err := serveFakeValidator(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	queryTimeout := 5 * time.Second
+	if value := os.Getenv("DB_QUERY_TIMEOUT"); value != "" {
+		queryTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Invalid DB_QUERY_TIMEOUT:", err)
+		}
+	}
 
 	e := echo.New()
 
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
+	e.Use(middleware.RequestID())
+
+	validatorAddr := os.Getenv("VALIDATOR_ADDR")
+	if validatorAddr == "" {
+		validatorAddr = os.Getenv("DOCKER_INTERNAL") + ":9000"
+	}
+	validatorTimeout := 2 * time.Second
+	if value := os.Getenv("VALIDATOR_TIMEOUT"); value != "" {
+		validatorTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Invalid VALIDATOR_TIMEOUT:", err)
+		}
+	}
+
+	validationWorkers, err := intEnv("VALIDATION_WORKERS", 4)
+	if err != nil {
+		log.Fatal(err)
+	}
+	validationQueueSize, err := intEnv("VALIDATION_QUEUE_SIZE", 100)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Deleted members can be restored until they are purged, after 30 days by
+	// default. A zero retention keeps them forever.
+	purgeRetention := 30 * 24 * time.Hour
+	if value := os.Getenv("PURGE_RETENTION"); value != "" {
+		purgeRetention, err = time.ParseDuration(value)
+		if err != nil || purgeRetention < 0 {
+			log.Fatal("Invalid PURGE_RETENTION: ", value)
+		}
+	}
+	purgeInterval := time.Hour
+	if value := os.Getenv("PURGE_INTERVAL"); value != "" {
+		purgeInterval, err = time.ParseDuration(value)
+		if err != nil || purgeInterval <= 0 {
+			log.Fatal("Invalid PURGE_INTERVAL: ", value)
+		}
+	}
+
+	// Processed outbox events are kept a week by default for the consumers of the
+	// event feed. A zero retention keeps them forever.
+	outboxRetention := 7 * 24 * time.Hour
+	if value := os.Getenv("OUTBOX_RETENTION"); value != "" {
+		outboxRetention, err = time.ParseDuration(value)
+		if err != nil || outboxRetention < 0 {
+			log.Fatal("Invalid OUTBOX_RETENTION: ", value)
+		}
+	}
 
-	// Database setup
-	db, err := sql.Open("postgres", "host="+dbHost+" port="+dbPort+" user="+dbUser+" password="+dbPassword+" dbname="+dbName+" sslmode=disable")
+	driver := os.Getenv("DB_DRIVER")
+	if driver == "" {
+		driver = "postgres"
+	}
+
+	var memberRepo repositories.MemberRepository
+	var dispatcher *validation.Dispatcher
+	var relay *outbox.Relay
+	var sender *webhooks.Sender
+	serverOpts := []grpcserver.Option{}
+	apiOpts := []api.Option{}
+	if header := os.Getenv("AUDIT_ACTOR_HEADER"); header != "" {
+		// The actor is set by the gateway, requests without it are rejected
+		serverOpts = append(serverOpts, grpcserver.WithActorMetadata(header))
+		apiOpts = append(apiOpts, api.WithActorHeader(header))
+	}
+	if value := os.Getenv("ADMIN_ACTORS"); value != "" {
+		// The admins may list the deleted members and read the whole audit trail
+		admins := strings.Split(value, ",")
+		for i := range admins {
+			admins[i] = strings.TrimSpace(admins[i])
+		}
+		apiOpts = append(apiOpts, api.WithAdmins(admins...))
+	}
+	if driver == "memory" {
+		// Nothing is persisted, which is enough to try the API without a database
+		if flag.Arg(0) == "migrate" {
+			log.Fatal("Migrations need a database, DB_DRIVER is memory")
+		}
+		memberRepo = repositories.NewMemoryRepository()
+	} else {
+		// Database setup
+		conn, err := openDB(driver)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+
+		if flag.Arg(0) == "migrate" {
+			if err := migrate(conn, driver, flag.Args()[1:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		if *migrateOnStart {
+			if err := migrate(conn, driver, []string{"up"}); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		// One connection to the validation service is shared by all requests
+		service, err := grpcclient.New(validatorAddr, grpcclient.WithTimeout(validatorTimeout))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer service.Close()
+		validator, err := newValidator(os.Getenv("VALIDATOR_MODE"), service, os.Getenv("VALIDATION_RULES"))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		opts := []repositories.Option{
+			repositories.WithQueryTimeout(queryTimeout),
+		}
+		relayOpts := []outbox.Option{outbox.WithRetention(outboxRetention)}
+		// Events are streamed once the transactions that could still commit
+		// earlier ones are over, which the query timeout bounds
+		feedOpts := []outbox.FeedOption{}
+		if queryTimeout > 0 {
+			feedOpts = append(feedOpts, outbox.WithSettleDelay(queryTimeout+time.Second))
+		}
+		senderOpts := []webhooks.Option{}
+		if os.Getenv("WEBHOOKS_ALLOW_PRIVATE") == "true" {
+			// Local development, where the receivers run on this machine
+			senderOpts = append(senderOpts, webhooks.WithPrivateNetworks())
+			apiOpts = append(apiOpts, api.WithPrivateWebhooks())
+		}
+		if driver == "sqlite" {
+			memberRepo = repositories.NewSQLiteRepository(conn, opts...)
+			relayOpts = append(relayOpts, outbox.WithoutRowLocks())
+			feedOpts = []outbox.FeedOption{outbox.WithSettleDelay(0)}
+			senderOpts = append(senderOpts, webhooks.WithoutRowLocks())
+		} else {
+			memberRepo = repositories.NewDBRepository(conn, opts...)
+		}
+
+		// Stored members are validated by a bounded pool of workers, fed with the
+		// events the repository writes to the outbox
+		dispatcher = validation.NewDispatcher(validator,
+			validation.WithWorkers(validationWorkers),
+			validation.WithQueueSize(validationQueueSize))
+		dispatcher.Start(memberRepo)
+		expvar.Publish("validation", expvar.Func(func() interface{} { return dispatcher.Stats() }))
+
+		relay = outbox.NewRelay(conn, relayOpts...)
+		relay.Handle(outbox.MemberCreated, dispatcher)
+		relay.Handle(outbox.MemberUpdated, dispatcher)
+		relay.Handle(outbox.MemberRevalidated, dispatcher)
+
+		// Every event is queued for the webhooks subscribed to it, then posted
+		// by the sender
+		webhookStore := webhooks.NewStore(conn)
+		for _, eventType := range webhooks.EventTypes {
+			relay.Handle(eventType, webhookStore)
+		}
+		sender = webhooks.NewSender(webhookStore, senderOpts...)
+		apiOpts = append(apiOpts, api.WithWebhooks(webhookStore))
+		feed := outbox.NewFeed(conn, feedOpts...)
+		serverOpts = append(serverOpts, grpcserver.WithEvents(feed))
+		apiOpts = append(apiOpts, api.WithEvents(feed))
+	}
+
+	api.RegisterRoutes(e, memberRepo, apiOpts...)
+
+	go func() {
+		if err := e.Start(":8080"); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Internal services reach the members over gRPC on their own port
+	grpcAddr := os.Getenv("GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = ":9090"
+	}
+	listener, err := net.Listen("tcp", grpcAddr)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
-	dbRepo := repositories.NewDBRepository(db)
+	memberService := grpcserver.New(memberRepo, serverOpts...)
+	grpcServer := memberService.GRPCServer()
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	log.Printf("gRPC member service listening on %s", listener.Addr())
+
+	// The expvar counters are only served on their own address, which must not
+	// be public, when DEBUG_ADDR is set
+	var debugServer *http.Server
+	if debugAddr := os.Getenv("DEBUG_ADDR"); debugAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/debug/vars", expvar.Handler())
+		debugServer = &http.Server{Addr: debugAddr, Handler: mux}
+		go func() {
+			if err := debugServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal(err)
+			}
+		}()
+		log.Printf("Debug variables served on %s", debugAddr)
+	}
+
+	var purgeJob *purge.Job
+	if purgeRetention > 0 {
+		purgeJob = purge.NewJob(memberRepo, purgeRetention, purge.WithInterval(purgeInterval))
+	}
 
-	api.RegisterRoutes(e, dbRepo)
+	// On SIGINT or SIGTERM, stop relaying events, sending webhooks and purging,
+	// finish the HTTP and gRPC requests, then the validations that were queued,
+	// before closing the connections. The events and webhook deliveries that were
+	// not delivered are sent after the restart.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		if relay != nil {
+			relay.Run(ctx)
+		}
+	}()
+	senderDone := make(chan struct{})
+	go func() {
+		defer close(senderDone)
+		if sender != nil {
+			sender.Run(ctx)
+		}
+	}()
+	purgeDone := make(chan struct{})
+	go func() {
+		defer close(purgeDone)
+		if purgeJob != nil {
+			purgeJob.Run(ctx)
+		}
+	}()
+	<-ctx.Done()
+	log.Print("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	<-relayDone
+	<-senderDone
+	<-purgeDone
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Could not finish serving requests: %v", err)
+	}
+	if debugServer != nil {
+		debugServer.Close()
+	}
+	memberService.Close()
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+	}
+	if dispatcher != nil {
+		if err := dispatcher.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Could not finish the queued validations: %v", err)
+		}
+	}
+}
+
+// intEnv reads an integer environment variable, returning fallback when unset.
+func intEnv(name string, fallback int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return n, nil
+}
+
+// newValidator returns the validator of the given mode: the validation service
+// (grpc, the default), the local rules (rules), the rules then the service
+// (chain), or the service falling back to the rules when it cannot be asked
+// (fallback). The rules are read from rulesPath, validation_rules.yaml by default.
+func newValidator(mode string, service *grpcclient.Client, rulesPath string) (validation.Validator, error) {
+	if mode == "" || mode == "grpc" {
+		return service, nil
+	}
+	if rulesPath == "" {
+		rulesPath = "validation_rules.yaml"
+	}
+	rules, err := validation.LoadRules(rulesPath)
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case "rules":
+		return rules, nil
+	case "chain":
+		return validation.Chain(rules, service), nil
+	case "fallback":
+		return validation.Fallback(service, rules), nil
+	}
+	return nil, fmt.Errorf("unknown VALIDATOR_MODE %q, use grpc, rules, chain or fallback", mode)
+}
+
+// openDB opens the database of the given driver, postgres or sqlite.
+func openDB(driver string) (*sql.DB, error) {
+	switch driver {
+	case "postgres":
+		dbHost := os.Getenv("DOCKER_INTERNAL")
+		dbPort := os.Getenv("DB_PORT")
+		dbUser := os.Getenv("DB_USER")
+		dbPassword := os.Getenv("DB_PASSWORD")
+		dbName := os.Getenv("DB_NAME")
+
+		return sql.Open("postgres", "host="+dbHost+" port="+dbPort+" user="+dbUser+" password="+dbPassword+" dbname="+dbName+" sslmode=disable")
+	case "sqlite":
+		path := os.Getenv("DB_PATH")
+		if path == "" {
+			path = "members.db"
+		}
+		conn, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+		if err != nil {
+			return nil, err
+		}
+		// SQLite has a single writer, one connection avoids busy errors
+		conn.SetMaxOpenConns(1)
+		return conn, nil
+	}
+	return nil, fmt.Errorf("unknown DB_DRIVER %q, use postgres, sqlite or memory", driver)
+}
+
+func migrate(conn *sql.DB, driver string, args []string) error {
+	files, err := db.Migrations(driver)
+	if err != nil {
+		return err
+	}
+	opts := []migrations.Option{}
+	if driver == "sqlite" {
+		opts = append(opts, migrations.WithoutLock())
+	}
+	runner, err := migrations.NewRunner(conn, files, opts...)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 || args[0] == "up" {
+		count, err := runner.Up(context.Background())
+		log.Printf("%d migration(s) applied", count)
+		return err
+	}
+	if args[0] != "down" {
+		return fmt.Errorf("unknown migrate command %q, use up or down", args[0])
+	}
+	steps := 1
+	if len(args) > 1 {
+		if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+			return fmt.Errorf("invalid number of migrations to revert %q", args[1])
+		}
+	}
+	count, err := runner.Down(context.Background(), steps)
+	log.Printf("%d migration(s) reverted", count)
+	return err
+}
+
+// serveFakeValidator serves the fake validation service until SIGINT or SIGTERM.
+func serveFakeValidator(args []string) error {
+	flags := flag.NewFlagSet("fake-validator", flag.ExitOnError)
+	addr := flags.String("addr", ":9000", "address to listen on")
+	configPath := flags.String("config", "", "YAML file of canned responses, every member is valid without it")
+	flags.Parse(args)
+
+	config := fakevalidator.Config{}
+	if *configPath != "" {
+		var err error
+		if config, err = fakevalidator.LoadConfig(*configPath); err != nil {
+			return err
+		}
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	server := fakevalidator.New(config).Serve(listener)
+	log.Printf("Fake validation service listening on %s", listener.Addr())
 
-	log.Fatal(e.Start(":8080"))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	server.GracefulStop()
+	return nil
 }